@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+	"github.com/mjossany/Chirpy/internal/entities"
+)
+
+func chirpFromDatabase(dbChirp database.Chirp) Chirp {
+	var editedAt *time.Time
+	if dbChirp.EditedAt.Valid {
+		editedAt = &dbChirp.EditedAt.Time
+	}
+
+	return Chirp{
+		ID:           dbChirp.ID,
+		CreatedAt:    dbChirp.CreatedAt,
+		UpdatedAt:    dbChirp.UpdatedAt,
+		Body:         dbChirp.Body,
+		UserID:       dbChirp.UserID,
+		ParentID:     dbChirp.ParentID,
+		RootID:       dbChirp.RootID,
+		Deleted:      dbChirp.DeletedAt.Valid,
+		LikeCount:    dbChirp.LikeCount,
+		RechirpCount: dbChirp.RechirpCount,
+		QuoteCount:   dbChirp.QuoteCount,
+		EditedAt:     editedAt,
+		rechirpOf:    dbChirp.RechirpOf,
+		quoteOf:      dbChirp.QuoteOf,
+		Mentions:     []Mention{},
+		Media:        []Attachment{},
+		isQuote:      dbChirp.IsQuote,
+	}
+}
+
+func chirpRefs(chirps []Chirp) []*Chirp {
+	refs := make([]*Chirp, len(chirps))
+	for i := range chirps {
+		refs[i] = &chirps[i]
+	}
+	return refs
+}
+
+// viewerID returns the user behind the request's access token, if it
+// carries a valid one. Public endpoints use it to personalise responses
+// and otherwise treat the caller as anonymous.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.NullUUID {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+// hydrateChirps fills in everything a response chirp needs beyond its own
+// row: the chirps that rechirps and quotes point at, mentions, media,
+// polls, and LikedByMe for the viewer. Embedded chirps get their own references
+// resolved one level further, so a rechirped quote still shows what it
+// quotes.
+func (cfg *apiConfig) hydrateChirps(ctx context.Context, viewer uuid.NullUUID, chirps ...*Chirp) error {
+	all := append([]*Chirp{}, chirps...)
+	level := chirps
+	for range 2 {
+		embedded, err := cfg.embedReferencedChirps(ctx, level)
+		if err != nil {
+			return err
+		}
+		all = append(all, embedded...)
+		level = embedded
+	}
+
+	err := cfg.attachMentions(ctx, all)
+	if err != nil {
+		return err
+	}
+	err = cfg.attachMedia(ctx, all)
+	if err != nil {
+		return err
+	}
+	err = cfg.attachPolls(ctx, viewer, all)
+	if err != nil {
+		return err
+	}
+	return cfg.markLikedByMe(ctx, viewer, all...)
+}
+
+func (cfg *apiConfig) attachMentions(ctx context.Context, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, c := range chirps {
+		ids[i] = c.ID
+	}
+	rows, err := cfg.db.ListChirpMentions(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	mentions := make(map[uuid.UUID][]Mention)
+	for _, row := range rows {
+		mentions[row.ChirpID] = append(mentions[row.ChirpID], Mention{
+			UserID: row.UserID,
+			Handle: row.Handle.String,
+			Start:  row.StartOffset,
+			End:    row.EndOffset,
+		})
+	}
+	for _, c := range chirps {
+		if m, ok := mentions[c.ID]; ok {
+			c.Mentions = m
+		}
+	}
+	return nil
+}
+
+// embedReferencedChirps attaches the originals of rechirps and quotes in
+// one query and returns the chirps it embedded.
+func (cfg *apiConfig) embedReferencedChirps(ctx context.Context, chirps []*Chirp) ([]*Chirp, error) {
+	var ids []uuid.UUID
+	for _, c := range chirps {
+		if c.rechirpOf.Valid {
+			ids = append(ids, c.rechirpOf.UUID)
+		}
+		if c.quoteOf.Valid {
+			ids = append(ids, c.quoteOf.UUID)
+		}
+	}
+
+	embedded := make(map[uuid.UUID]*Chirp, len(ids))
+	if len(ids) > 0 {
+		dbChirps, err := cfg.db.GetChirpsByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, dbChirp := range dbChirps {
+			if dbChirp.DeletedAt.Valid {
+				continue
+			}
+			c := chirpFromDatabase(dbChirp)
+			embedded[c.ID] = &c
+		}
+	}
+
+	for _, c := range chirps {
+		if c.rechirpOf.Valid {
+			c.Rechirped = embedded[c.rechirpOf.UUID]
+		}
+		if c.isQuote {
+			if original, ok := embedded[c.quoteOf.UUID]; ok && c.quoteOf.Valid {
+				c.Quoted = &QuotedChirp{Chirp: original}
+			} else {
+				c.Quoted = &QuotedChirp{Unavailable: true, Placeholder: "chirp unavailable"}
+			}
+		}
+	}
+
+	refs := make([]*Chirp, 0, len(embedded))
+	for _, c := range embedded {
+		refs = append(refs, c)
+	}
+	return refs, nil
+}
+
+// markLikedByMe fills in LikedByMe for an authenticated viewer and leaves
+// it unset for anonymous ones.
+func (cfg *apiConfig) markLikedByMe(ctx context.Context, viewer uuid.NullUUID, chirps ...*Chirp) error {
+	if !viewer.Valid || len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, c := range chirps {
+		ids[i] = c.ID
+	}
+	likedIDs, err := cfg.db.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{
+		UserID:   viewer.UUID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	liked := make(map[uuid.UUID]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+	for _, c := range chirps {
+		likedByMe := liked[c.ID]
+		c.LikedByMe = &likedByMe
+	}
+	return nil
+}
+
+// saveChirpEntities indexes the hashtags and mentions in a chirp's body,
+// replacing any earlier index for it, and notifies mentioned users. Call it
+// inside the transaction that writes the body, and publish the returned
+// notifications once it commits.
+func saveChirpEntities(ctx context.Context, qtx *database.Queries, dbChirp database.Chirp) ([]database.Notification, error) {
+	notifications, err := saveChirpMentions(ctx, qtx, dbChirp)
+	if err != nil {
+		return nil, err
+	}
+
+	err = qtx.DeleteChirpHashtags(ctx, dbChirp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var tags []string
+	for _, hashtag := range entities.Hashtags(dbChirp.Body) {
+		tag := entities.NormalizeHashtag(hashtag.Text)
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return notifications, nil
+	}
+
+	err = qtx.CreateHashtags(ctx, tags)
+	if err != nil {
+		return nil, err
+	}
+	err = qtx.AddChirpHashtags(ctx, database.AddChirpHashtagsParams{
+		ChirpID:   dbChirp.ID,
+		CreatedAt: dbChirp.CreatedAt,
+		Tags:      tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// saveChirpMentions resolves @handles against users and stores the ones
+// that match. Each mentioned user is notified once per chirp, however many
+// times the chirp is edited, and never for mentioning themselves.
+func saveChirpMentions(ctx context.Context, qtx *database.Queries, dbChirp database.Chirp) ([]database.Notification, error) {
+	err := qtx.DeleteChirpMentions(ctx, dbChirp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := entities.Mentions(dbChirp.Body)
+	if len(mentions) == 0 {
+		return nil, nil
+	}
+
+	handles := make([]string, len(mentions))
+	for i, mention := range mentions {
+		handles[i] = entities.NormalizeHandle(mention.Text)
+	}
+	users, err := qtx.GetUsersByHandles(ctx, handles)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make(map[string]uuid.UUID, len(users))
+	for _, user := range users {
+		userIDs[entities.NormalizeHandle(user.Handle.String)] = user.ID
+	}
+
+	var notifications []database.Notification
+	notified := make(map[uuid.UUID]bool)
+	for _, mention := range mentions {
+		userID, ok := userIDs[entities.NormalizeHandle(mention.Text)]
+		if !ok {
+			continue
+		}
+		err = qtx.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+			ChirpID:     dbChirp.ID,
+			UserID:      userID,
+			StartOffset: int32(mention.Start),
+			EndOffset:   int32(mention.End),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if notified[userID] {
+			continue
+		}
+		notified[userID] = true
+		created, err := notify(ctx, qtx, userID, dbChirp.UserID, notificationKindMention, uuid.NullUUID{UUID: dbChirp.ID, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, created...)
+	}
+	return notifications, nil
+}
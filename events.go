@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/database"
+	"github.com/mjossany/Chirpy/internal/entities"
+)
+
+const (
+	eventChirpCreated = "chirp"
+	eventChirpDeleted = "chirp_deleted"
+	eventNotification = "notification"
+
+	eventFollowsChanged = "follows_changed"
+)
+
+// topicChirps carries every new and deleted chirp, which are also
+// published to their author's topic and one topic per hashtag in them.
+// Notifications go to a per-user topic so only their recipient sees them,
+// and follow changes to one so open sockets can refresh their timelines.
+const topicChirps = "chirps"
+
+func userTopic(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+func hashtagTopic(tag string) string {
+	return "hashtag:" + tag
+}
+
+func notificationTopic(userID uuid.UUID) string {
+	return "notifications:" + userID.String()
+}
+
+func followsTopic(userID uuid.UUID) string {
+	return "follows:" + userID.String()
+}
+
+func chirpTopics(dbChirp database.Chirp) []string {
+	topics := []string{topicChirps, userTopic(dbChirp.UserID)}
+	seen := make(map[string]bool)
+	for _, hashtag := range entities.Hashtags(dbChirp.Body) {
+		tag := entities.NormalizeHashtag(hashtag.Text)
+		if !seen[tag] {
+			seen[tag] = true
+			topics = append(topics, hashtagTopic(tag))
+		}
+	}
+	return topics
+}
+
+func (cfg *apiConfig) publish(eventType string, payload interface{}, topics ...string) {
+	dat, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling %s event: %s", eventType, err)
+		return
+	}
+	cfg.events.Publish(eventType, dat, topics...)
+}
+
+// publishChirp announces a newly created chirp. It is hydrated without a
+// viewer since the same event goes to everyone.
+func (cfg *apiConfig) publishChirp(ctx context.Context, dbChirp database.Chirp) {
+	chirp := chirpFromDatabase(dbChirp)
+	err := cfg.hydrateChirps(ctx, uuid.NullUUID{}, &chirp)
+	if err != nil {
+		log.Printf("Couldn't load chirp for event: %s", err)
+		return
+	}
+	cfg.publish(eventChirpCreated, chirp, chirpTopics(dbChirp)...)
+}
+
+// publishChirpDeleted announces that dbChirp, as it was before deletion, is
+// gone.
+func (cfg *apiConfig) publishChirpDeleted(dbChirp database.Chirp) {
+	type payload struct {
+		ID uuid.UUID `json:"id"`
+	}
+	cfg.publish(eventChirpDeleted, payload{ID: dbChirp.ID}, chirpTopics(dbChirp)...)
+}
+
+// publishFollowsChanged tells userID's open sockets that who they follow
+// has changed.
+func (cfg *apiConfig) publishFollowsChanged(userID uuid.UUID) {
+	cfg.publish(eventFollowsChanged, struct{}{}, followsTopic(userID))
+}
+
+func (cfg *apiConfig) publishNotifications(notifications ...database.Notification) {
+	for _, n := range notifications {
+		cfg.publish(eventNotification, Notification{
+			ID:        n.ID,
+			Kind:      n.Kind,
+			ActorID:   n.ActorID,
+			ChirpID:   n.ChirpID,
+			Summary:   notificationSummary(n.Kind, 1),
+			CreatedAt: n.CreatedAt,
+		}, notificationTopic(n.UserID))
+	}
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+const maxBookmarkFolderNameLength = 50
+
+// Bookmark is a chirp the caller has saved. FolderID is null for
+// bookmarks that aren't filed in a folder.
+type Bookmark struct {
+	Chirp        Chirp         `json:"chirp"`
+	FolderID     uuid.NullUUID `json:"folder_id"`
+	BookmarkedAt time.Time     `json:"bookmarked_at"`
+}
+
+type BookmarkFolder struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Name          string    `json:"name"`
+	BookmarkCount int64     `json:"bookmark_count"`
+}
+
+type bookmarkPage struct {
+	Bookmarks  []Bookmark `json:"bookmarks"`
+	NextCursor *string    `json:"next_cursor"`
+}
+
+func bookmarkFolderFromDatabase(dbFolder database.BookmarkFolder) BookmarkFolder {
+	return BookmarkFolder{
+		ID:        dbFolder.ID,
+		CreatedAt: dbFolder.CreatedAt,
+		UpdatedAt: dbFolder.UpdatedAt,
+		Name:      dbFolder.Name,
+	}
+}
+
+// handleBookmarkChirp saves a chirp for the caller, optionally into one of
+// their folders. Bookmarking an already bookmarked chirp moves it to the
+// given folder, or out of any folder when none is given. A rechirp is
+// bookmarked as the chirp it shares.
+func (cfg *apiConfig) handleBookmarkChirp(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	type parameters struct {
+		FolderID uuid.NullUUID `json:"folder_id"`
+	}
+
+	// The body is optional: without one the bookmark isn't filed.
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	dbChirp, err := cfg.getShareableChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+
+	if params.FolderID.Valid {
+		_, err = cfg.db.GetBookmarkFolder(r.Context(), database.GetBookmarkFolderParams{
+			ID:     params.FolderID.UUID,
+			UserID: userID,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, 404, "Couldn't find bookmark folder", err)
+				return
+			}
+			respondWithError(w, 500, "Couldn't find bookmark folder", err)
+			return
+		}
+	}
+
+	dbBookmark, err := cfg.db.BookmarkChirp(r.Context(), database.BookmarkChirpParams{
+		UserID:   userID,
+		ChirpID:  dbChirp.ID,
+		FolderID: params.FolderID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't bookmark chirp", err)
+		return
+	}
+
+	chirp := chirpFromDatabase(dbChirp)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, &chirp)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 201, Bookmark{
+		Chirp:        chirp,
+		FolderID:     dbBookmark.FolderID,
+		BookmarkedAt: dbBookmark.CreatedAt,
+	})
+}
+
+func (cfg *apiConfig) handleUnbookmarkChirp(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	dbChirp, err := cfg.getShareableChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+
+	err = cfg.db.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+		UserID:  userID,
+		ChirpID: dbChirp.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't remove bookmark", err)
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+// handleBookmarkList lists the caller's own bookmarks, most recent first,
+// optionally only those in one folder.
+func (cfg *apiConfig) handleBookmarkList(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	page, err := parseFeedPageRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	folderID := uuid.NullUUID{}
+	if folder := r.URL.Query().Get("folder_id"); folder != "" {
+		folderUUID, err := uuid.Parse(folder)
+		if err != nil {
+			respondWithError(w, 400, "Invalid folder id", err)
+			return
+		}
+		_, err = cfg.db.GetBookmarkFolder(r.Context(), database.GetBookmarkFolderParams{
+			ID:     folderUUID,
+			UserID: userID,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, 404, "Couldn't find bookmark folder", err)
+				return
+			}
+			respondWithError(w, 500, "Couldn't find bookmark folder", err)
+			return
+		}
+		folderID = uuid.NullUUID{UUID: folderUUID, Valid: true}
+	}
+
+	cursorCreatedAt, cursorID := page.keyset()
+	rows, err := cfg.db.ListBookmarks(r.Context(), database.ListBookmarksParams{
+		UserID:          userID,
+		FolderID:        folderID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get bookmarks", err)
+		return
+	}
+
+	rows, next, _ := paginate(page, rows, func(row database.ListBookmarksRow) (time.Time, uuid.UUID) {
+		return row.BookmarkedAt, row.Chirp.ID
+	})
+
+	bookmarks := make([]Bookmark, len(rows))
+	chirps := make([]*Chirp, len(rows))
+	for i, row := range rows {
+		bookmarks[i] = Bookmark{
+			Chirp:        chirpFromDatabase(row.Chirp),
+			FolderID:     row.FolderID,
+			BookmarkedAt: row.BookmarkedAt,
+		}
+		chirps[i] = &bookmarks[i].Chirp
+	}
+
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps...)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 200, bookmarkPage{
+		Bookmarks:  bookmarks,
+		NextCursor: next,
+	})
+}
+
+func validateBookmarkFolderName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "Folder name can't be blank"
+	}
+	if len([]rune(name)) > maxBookmarkFolderNameLength {
+		return "", "Folder name is too long"
+	}
+	return name, ""
+}
+
+func (cfg *apiConfig) handleBookmarkFolderList(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	rows, err := cfg.db.ListBookmarkFolders(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get bookmark folders", err)
+		return
+	}
+
+	folders := make([]BookmarkFolder, len(rows))
+	for i, row := range rows {
+		folders[i] = bookmarkFolderFromDatabase(row.BookmarkFolder)
+		folders[i].BookmarkCount = row.BookmarkCount
+	}
+
+	respondWithJSON(w, 200, folders)
+}
+
+func (cfg *apiConfig) handleBookmarkFolderCreate(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	type parameters struct {
+		Name string `json:"name"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	name, msg := validateBookmarkFolderName(params.Name)
+	if msg != "" {
+		respondWithError(w, 400, msg, nil)
+		return
+	}
+
+	dbFolder, err := cfg.db.CreateBookmarkFolder(r.Context(), database.CreateBookmarkFolderParams{
+		UserID: userID,
+		Name:   name,
+	})
+	if err != nil {
+		if isUniqueViolation(err, "bookmark_folders_user_name_key") {
+			respondWithError(w, 409, "You already have a folder with that name", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't create bookmark folder", err)
+		return
+	}
+
+	respondWithJSON(w, 201, bookmarkFolderFromDatabase(dbFolder))
+}
+
+func (cfg *apiConfig) handleBookmarkFolderRename(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	folderUUID, err := uuid.Parse(r.PathValue("folderID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid folder id", err)
+		return
+	}
+
+	type parameters struct {
+		Name string `json:"name"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	name, msg := validateBookmarkFolderName(params.Name)
+	if msg != "" {
+		respondWithError(w, 400, msg, nil)
+		return
+	}
+
+	dbFolder, err := cfg.db.RenameBookmarkFolder(r.Context(), database.RenameBookmarkFolderParams{
+		Name:   name,
+		ID:     folderUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find bookmark folder", err)
+			return
+		}
+		if isUniqueViolation(err, "bookmark_folders_user_name_key") {
+			respondWithError(w, 409, "You already have a folder with that name", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't rename bookmark folder", err)
+		return
+	}
+
+	respondWithJSON(w, 200, bookmarkFolderFromDatabase(dbFolder))
+}
+
+// handleBookmarkFolderDelete removes a folder. Its bookmarks are kept and
+// become unfiled.
+func (cfg *apiConfig) handleBookmarkFolderDelete(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	folderUUID, err := uuid.Parse(r.PathValue("folderID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid folder id", err)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteBookmarkFolder(r.Context(), database.DeleteBookmarkFolderParams{
+		ID:     folderUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't delete bookmark folder", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Couldn't find bookmark folder", nil)
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
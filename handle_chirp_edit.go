@@ -0,0 +1,198 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleChirpEdit(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	if len(params.Body) > maxChirpLength {
+		respondWithError(w, http.StatusBadRequest, "Chirp is too long", nil)
+		return
+	}
+
+	cleanedChirp := cleanChirp(params.Body)
+
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Couldn't find chirp", nil)
+		return
+	}
+
+	if dbChirp.UserID != userID {
+		respondWithError(w, 403, "Unauthorized action", nil)
+		return
+	}
+
+	if dbChirp.RechirpOf.Valid {
+		respondWithError(w, 400, "Rechirps can't be edited", nil)
+		return
+	}
+
+	if cfg.editWindow > 0 && time.Now().UTC().Sub(dbChirp.CreatedAt) > cfg.editWindow {
+		respondWithError(w, 403, "Chirp can no longer be edited", nil)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't find user", err)
+		return
+	}
+	if !dbUser.EmailVerifiedAt.Valid {
+		respondWithError(w, 403, "Verify your email address before chirping", nil)
+		return
+	}
+	if cfg.editRequiresRed && !dbUser.IsChirpyRed {
+		respondWithError(w, 403, "Editing chirps requires Chirpy Red", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't edit chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	versionCreatedAt := dbChirp.CreatedAt
+	if dbChirp.EditedAt.Valid {
+		versionCreatedAt = dbChirp.EditedAt.Time
+	}
+	_, err = qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+		ChirpID:   dbChirp.ID,
+		Body:      dbChirp.Body,
+		CreatedAt: versionCreatedAt,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't save chirp revision", err)
+		return
+	}
+
+	dbChirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		Body:   cleanedChirp,
+		ID:     chirpUUID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't edit chirp", err)
+		return
+	}
+
+	notifications, err := saveChirpEntities(r.Context(), qtx, dbChirp)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't save chirp entities", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't edit chirp", err)
+		return
+	}
+
+	cfg.publishNotifications(notifications...)
+
+	chirp := chirpFromDatabase(dbChirp)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, &chirp)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 200, chirp)
+}
+
+func (cfg *apiConfig) handleChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Couldn't find chirp", nil)
+		return
+	}
+
+	dbRevisions, err := cfg.db.ListChirpRevisions(r.Context(), chirpUUID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get revisions", err)
+		return
+	}
+
+	type revision struct {
+		ID         uuid.UUID `json:"id"`
+		Body       string    `json:"body"`
+		CreatedAt  time.Time `json:"created_at"`
+		ReplacedAt time.Time `json:"replaced_at"`
+	}
+
+	revisions := make([]revision, len(dbRevisions))
+	for i, dbRevision := range dbRevisions {
+		revisions[i] = revision{
+			ID:         dbRevision.ID,
+			Body:       dbRevision.Body,
+			CreatedAt:  dbRevision.CreatedAt,
+			ReplacedAt: dbRevision.ReplacedAt,
+		}
+	}
+
+	respondWithJSON(w, 200, revisions)
+}
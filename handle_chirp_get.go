@@ -25,11 +25,12 @@ func (cfg *apiConfig) handleGetChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondWithJSON(w, 200, Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
-	})
+	chirp := chirpFromDatabase(dbChirp)
+	err = cfg.hydrateChirps(r.Context(), cfg.viewerID(r), &chirp)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 200, chirp)
 }
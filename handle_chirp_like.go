@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleLikeChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpLike(w, r, true)
+}
+
+func (cfg *apiConfig) handleUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpLike(w, r, false)
+}
+
+// setChirpLike records or removes the caller's like and moves like_count
+// by the same amount in one transaction, so repeating either call is a
+// no-op rather than drifting the count.
+func (cfg *apiConfig) setChirpLike(w http.ResponseWriter, r *http.Request, like bool) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Couldn't find chirp", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't update like", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	var changed int64
+	if like {
+		changed, err = qtx.LikeChirp(r.Context(), database.LikeChirpParams{
+			UserID:  userID,
+			ChirpID: chirpUUID,
+		})
+	} else {
+		changed, err = qtx.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+			UserID:  userID,
+			ChirpID: chirpUUID,
+		})
+		changed = -changed
+	}
+	if err != nil {
+		respondWithError(w, 500, "Couldn't update like", err)
+		return
+	}
+
+	likeCount, err := qtx.AdjustChirpLikeCount(r.Context(), database.AdjustChirpLikeCountParams{
+		Delta: int32(changed),
+		ID:    chirpUUID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't update like count", err)
+		return
+	}
+
+	var notifications []database.Notification
+	if changed > 0 {
+		notifications, err = notify(r.Context(), qtx, dbChirp.UserID, userID, notificationKindLike, uuid.NullUUID{UUID: chirpUUID, Valid: true})
+		if err != nil {
+			respondWithError(w, 500, "Couldn't create notification", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't update like", err)
+		return
+	}
+
+	cfg.publishNotifications(notifications...)
+
+	type response struct {
+		LikeCount int32 `json:"like_count"`
+		LikedByMe bool  `json:"liked_by_me"`
+	}
+
+	respondWithJSON(w, 200, response{
+		LikeCount: likeCount,
+		LikedByMe: like,
+	})
+}
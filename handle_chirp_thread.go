@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+type ThreadChirp struct {
+	Chirp
+	Depth int `json:"depth"`
+}
+
+func (cfg *apiConfig) handleChirpThread(w http.ResponseWriter, r *http.Request) {
+	chirpID := r.PathValue("chirpID")
+	if chirpID == "" {
+		respondWithError(w, 404, "chirpID must not be blank", nil)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(chirpID)
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+
+	rootID := dbChirp.ID
+	if dbChirp.RootID.Valid {
+		rootID = dbChirp.RootID.UUID
+	}
+
+	dbChirps, err := cfg.db.GetThreadChirps(r.Context(), rootID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get thread", err)
+		return
+	}
+
+	thread := flattenThread(rootID, dbChirps)
+	refs := make([]*Chirp, len(thread))
+	for i := range thread {
+		refs[i] = &thread[i].Chirp
+	}
+	err = cfg.hydrateChirps(r.Context(), cfg.viewerID(r), refs...)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	type response struct {
+		RootID uuid.UUID     `json:"root_id"`
+		Chirps []ThreadChirp `json:"chirps"`
+	}
+
+	respondWithJSON(w, 200, response{
+		RootID: rootID,
+		Chirps: thread,
+	})
+}
+
+// flattenThread orders a thread depth-first, replies under their parent
+// oldest first. dbChirps must already be sorted by creation time. Replies
+// whose parent is gone are hung off the root.
+func flattenThread(rootID uuid.UUID, dbChirps []database.Chirp) []ThreadChirp {
+	inThread := make(map[uuid.UUID]bool, len(dbChirps))
+	for _, c := range dbChirps {
+		inThread[c.ID] = true
+	}
+
+	var root *database.Chirp
+	replies := make(map[uuid.UUID][]database.Chirp)
+	for i, c := range dbChirps {
+		if c.ID == rootID {
+			root = &dbChirps[i]
+			continue
+		}
+		parentID := rootID
+		if c.ParentID.Valid && inThread[c.ParentID.UUID] {
+			parentID = c.ParentID.UUID
+		}
+		replies[parentID] = append(replies[parentID], c)
+	}
+
+	thread := make([]ThreadChirp, 0, len(dbChirps))
+	var walk func(c database.Chirp, depth int)
+	walk = func(c database.Chirp, depth int) {
+		thread = append(thread, ThreadChirp{Chirp: chirpFromDatabase(c), Depth: depth})
+		for _, reply := range replies[c.ID] {
+			walk(reply, depth+1)
+		}
+	}
+
+	if root != nil {
+		walk(*root, 0)
+	} else {
+		for _, reply := range replies[rootID] {
+			walk(reply, 1)
+		}
+	}
+	return thread
+}
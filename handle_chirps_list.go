@@ -1,62 +1,77 @@
 package main
 
 import (
-	"database/sql"
 	"net/http"
-	"sort"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mjossany/Chirpy/internal/database"
 )
 
-func (cfg *apiConfig) handleChirpList(w http.ResponseWriter, r *http.Request) {
-	var dbChirps []database.Chirp
-	var err error
-
-	authorID := r.URL.Query().Get("author_id")
-	sortOrder := r.URL.Query().Get("sort")
+type chirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor"`
+}
 
-	if sortOrder == "" || (sortOrder != "asc" && sortOrder != "desc") {
-		sortOrder = "asc"
+func (cfg *apiConfig) handleChirpList(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
 	}
 
-	if authorID != "" {
-		authorUUID, err := uuid.Parse(authorID)
+	authorUUID := uuid.NullUUID{}
+	if authorID := r.URL.Query().Get("author_id"); authorID != "" {
+		authorUUID.UUID, err = uuid.Parse(authorID)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
 			return
 		}
-		dbChirps, err = cfg.db.GetChirpsByUserID(r.Context(), authorUUID)
-	} else {
-		dbChirps, err = cfg.db.GetAllChirps(r.Context())
+		authorUUID.Valid = true
 	}
 
+	cursorCreatedAt, cursorID := page.keyset()
+
+	var dbChirps []database.Chirp
+	if page.ascending() {
+		dbChirps, err = cfg.db.ListChirpsAfter(r.Context(), database.ListChirpsAfterParams{
+			AuthorID:        authorUUID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        page.fetchSize(),
+		})
+	} else {
+		dbChirps, err = cfg.db.ListChirpsBefore(r.Context(), database.ListChirpsBeforeParams{
+			AuthorID:        authorUUID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        page.fetchSize(),
+		})
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
-			respondWithJSON(w, http.StatusOK, []Chirp{})
-			return
-		}
 		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirps", err)
 		return
 	}
 
+	dbChirps, next, prev := paginate(page, dbChirps, func(c database.Chirp) (time.Time, uuid.UUID) {
+		return c.CreatedAt, c.ID
+	})
+
 	responseChirps := make([]Chirp, len(dbChirps))
 	for i, dbChirp := range dbChirps {
-		responseChirps[i] = Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
-		}
+		responseChirps[i] = chirpFromDatabase(dbChirp)
 	}
 
-	sort.Slice(responseChirps, func(i, j int) bool {
-		if sortOrder == "desc" {
-			return responseChirps[i].CreatedAt.After(responseChirps[j].CreatedAt)
-		}
-		return responseChirps[i].CreatedAt.Before(responseChirps[j].CreatedAt)
-	})
+	err = cfg.hydrateChirps(r.Context(), cfg.viewerID(r), chirpRefs(responseChirps)...)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
 
-	respondWithJSON(w, 200, responseChirps)
+	respondWithJSON(w, 200, chirpPage{
+		Chirps:     responseChirps,
+		NextCursor: next,
+		PrevCursor: prev,
+	})
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -34,7 +35,17 @@ func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't delete chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Locking the chirp holds back replies to it until this commits, so
+	// none can slip in between checking for replies and deleting.
+	dbChirp, err := qtx.GetChirpForUpdate(r.Context(), chirpUUID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			respondWithError(w, 404, "Couldn't find chirp", err)
@@ -44,19 +55,120 @@ func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Couldn't find chirp", nil)
+		return
+	}
+
 	if dbChirp.UserID != userID {
 		respondWithError(w, 403, "Unauthorized action", err)
 		return
 	}
 
-	err = cfg.db.DeleteChirp(r.Context(), database.DeleteChirpParams{
-		ID:     chirpUUID,
-		UserID: userID,
-	})
+	hasReplies, err := qtx.HasReplies(r.Context(), chirpUUID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't delete chirp", err)
+		return
+	}
+
+	// A chirp with replies is blanked out rather than removed so the rest
+	// of its thread keeps its shape. Its rechirps go either way, deleted
+	// here rather than by the cascade so they can be announced; quotes of
+	// it fall back to a placeholder.
+	rechirps, err := qtx.DeleteRechirpsOf(r.Context(), uuid.NullUUID{UUID: chirpUUID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't delete chirp", err)
+		return
+	}
+	if hasReplies {
+		err = qtx.DeleteChirpHashtags(r.Context(), chirpUUID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't delete chirp", err)
+			return
+		}
+		err = qtx.DeleteChirpMentions(r.Context(), chirpUUID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't delete chirp", err)
+			return
+		}
+		err = qtx.DeleteChirpAttachments(r.Context(), chirpUUID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't delete chirp", err)
+			return
+		}
+		err = qtx.DeletePoll(r.Context(), chirpUUID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't delete chirp", err)
+			return
+		}
+		err = qtx.DeleteChirpBookmarks(r.Context(), chirpUUID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't delete chirp", err)
+			return
+		}
+		err = qtx.DeleteChirpPins(r.Context(), chirpUUID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't delete chirp", err)
+			return
+		}
+		err = qtx.TombstoneChirp(r.Context(), database.TombstoneChirpParams{
+			ID:     chirpUUID,
+			UserID: userID,
+		})
+	} else {
+		err = qtx.DeleteChirp(r.Context(), database.DeleteChirpParams{
+			ID:     chirpUUID,
+			UserID: userID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, 500, "Couldn't delete chirp", err)
+		return
+	}
+
+	if dbChirp.RechirpOf.Valid || dbChirp.QuoteOf.Valid {
+		counts := database.AdjustChirpShareCountsParams{ID: dbChirp.QuoteOf.UUID}
+		if dbChirp.RechirpOf.Valid {
+			counts = database.AdjustChirpShareCountsParams{ID: dbChirp.RechirpOf.UUID}
+			counts.RechirpDelta = -1
+		} else {
+			counts.QuoteDelta = -1
+		}
+		err = qtx.AdjustChirpShareCounts(r.Context(), counts)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't update share counts", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		respondWithError(w, 500, "Couldn't delete chirp", err)
 		return
 	}
 
+	cfg.publishChirpDeleted(dbChirp)
+	for _, rechirp := range rechirps {
+		cfg.publishChirpDeleted(rechirp)
+	}
+
+	if hasReplies {
+		respondWithJSON(w, 204, nil)
+		return
+	}
+
+	// Removing the last reply under a tombstone leaves nothing worth
+	// keeping, so clear out any tombstones this orphaned on the way up.
+	parentID := dbChirp.ParentID
+	for parentID.Valid {
+		parentID, err = cfg.db.DeleteOrphanedTombstone(r.Context(), parentID.UUID)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				log.Printf("Couldn't clean up tombstone: %s", err)
+			}
+			break
+		}
+	}
+
 	respondWithJSON(w, 204, nil)
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleFollow(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	followeeUUID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID", err)
+		return
+	}
+
+	if followeeUUID == userID {
+		respondWithError(w, 400, "You can't follow yourself", nil)
+		return
+	}
+
+	_, err = cfg.db.GetUserByID(r.Context(), followeeUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "User can't be found", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find user", err)
+		return
+	}
+
+	followed, err := cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeUUID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't follow user", err)
+		return
+	}
+
+	if followed > 0 {
+		notifications, err := notify(r.Context(), cfg.db, followeeUUID, userID, notificationKindFollow, uuid.NullUUID{})
+		if err != nil {
+			respondWithError(w, 500, "Couldn't create notification", err)
+			return
+		}
+		cfg.publishNotifications(notifications...)
+		cfg.publishFollowsChanged(userID)
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) handleUnfollow(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	followeeUUID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID", err)
+		return
+	}
+
+	err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeUUID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't unfollow user", err)
+		return
+	}
+
+	cfg.publishFollowsChanged(userID)
+
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+type FollowedUser struct {
+	ID          uuid.UUID `json:"id"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	FollowedAt  time.Time `json:"followed_at"`
+}
+
+type followPage struct {
+	Users      []FollowedUser `json:"users"`
+	NextCursor *string        `json:"next_cursor"`
+}
+
+func (cfg *apiConfig) handleFollowerList(w http.ResponseWriter, r *http.Request) {
+	cfg.respondWithFollowPage(w, r, func(arg database.ListFollowersParams) ([]database.ListFollowersRow, error) {
+		return cfg.db.ListFollowers(r.Context(), arg)
+	})
+}
+
+func (cfg *apiConfig) handleFollowingList(w http.ResponseWriter, r *http.Request) {
+	cfg.respondWithFollowPage(w, r, func(arg database.ListFollowersParams) ([]database.ListFollowersRow, error) {
+		rows, err := cfg.db.ListFollowing(r.Context(), database.ListFollowingParams(arg))
+		followers := make([]database.ListFollowersRow, len(rows))
+		for i, row := range rows {
+			followers[i] = database.ListFollowersRow(row)
+		}
+		return followers, err
+	})
+}
+
+// respondWithFollowPage serves one page of either side of the follow graph
+// for the user in the {userID} path segment.
+func (cfg *apiConfig) respondWithFollowPage(w http.ResponseWriter, r *http.Request, list func(database.ListFollowersParams) ([]database.ListFollowersRow, error)) {
+	userUUID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID", err)
+		return
+	}
+
+	page, err := parseFeedPageRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	_, err = cfg.db.GetUserByID(r.Context(), userUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "User can't be found", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find user", err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.keyset()
+	rows, err := list(database.ListFollowersParams{
+		UserID:          userUUID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get users", err)
+		return
+	}
+
+	rows, next, _ := paginate(page, rows, func(row database.ListFollowersRow) (time.Time, uuid.UUID) {
+		return row.FollowedAt, row.ID
+	})
+
+	users := make([]FollowedUser, len(rows))
+	for i, row := range rows {
+		users[i] = FollowedUser{
+			ID:          row.ID,
+			IsChirpyRed: row.IsChirpyRed,
+			FollowedAt:  row.FollowedAt,
+		}
+	}
+
+	respondWithJSON(w, 200, followPage{
+		Users:      users,
+		NextCursor: next,
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/database"
+	"github.com/mjossany/Chirpy/internal/entities"
+)
+
+const (
+	defaultTrendingWindow = time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	// trendingBaselineWindows is how many windows before the current one
+	// make up a tag's normal rate of use.
+	trendingBaselineWindows = 24
+	trendingMinUses         = 2
+	trendingLimit           = 10
+)
+
+func (cfg *apiConfig) handleHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := entities.NormalizeHashtag(r.PathValue("tag"))
+	if tag == "" {
+		respondWithError(w, 404, "tag must not be blank", nil)
+		return
+	}
+
+	page, err := parseFeedPageRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.keyset()
+	dbChirps, err := cfg.db.ListHashtagChirps(r.Context(), database.ListHashtagChirpsParams{
+		Tag:             tag,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get chirps", err)
+		return
+	}
+
+	dbChirps, next, _ := paginate(page, dbChirps, func(c database.Chirp) (time.Time, uuid.UUID) {
+		return c.CreatedAt, c.ID
+	})
+
+	responseChirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		responseChirps[i] = chirpFromDatabase(dbChirp)
+	}
+
+	err = cfg.hydrateChirps(r.Context(), cfg.viewerID(r), chirpRefs(responseChirps)...)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 200, chirpPage{
+		Chirps:     responseChirps,
+		NextCursor: next,
+	})
+}
+
+// handleTrending ranks tags by how far their use in the latest window runs
+// ahead of their usual rate, so a steady giant doesn't crowd out a tag
+// that is suddenly taking off.
+func (cfg *apiConfig) handleTrending(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if param := r.URL.Query().Get("window"); param != "" {
+		d, err := time.ParseDuration(param)
+		if err != nil || d <= 0 || d > maxTrendingWindow {
+			respondWithError(w, http.StatusBadRequest, "Invalid window", err)
+			return
+		}
+		window = d
+	}
+
+	now := time.Now().UTC()
+	windowStart := now.Add(-window)
+	rows, err := cfg.db.ListTrendingHashtags(r.Context(), database.ListTrendingHashtagsParams{
+		WindowStart:     windowStart,
+		BaselineStart:   windowStart.Add(-window * trendingBaselineWindows),
+		MinUses:         trendingMinUses,
+		BaselineWindows: trendingBaselineWindows,
+		MaxResults:      trendingLimit,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get trending hashtags", err)
+		return
+	}
+
+	type trend struct {
+		Tag          string  `json:"tag"`
+		RecentUses   int64   `json:"recent_uses"`
+		BaselineRate float64 `json:"baseline_rate"`
+		Score        float64 `json:"score"`
+	}
+
+	type response struct {
+		Window string  `json:"window"`
+		Trends []trend `json:"trends"`
+	}
+
+	trends := make([]trend, len(rows))
+	for i, row := range rows {
+		baselineRate := float64(row.BaselineUses) / trendingBaselineWindows
+		trends[i] = trend{
+			Tag:          row.Tag,
+			RecentUses:   row.RecentUses,
+			BaselineRate: baselineRate,
+			Score:        float64(row.RecentUses) / (baselineRate + 1),
+		}
+	}
+
+	respondWithJSON(w, 200, response{
+		Window: window.String(),
+		Trends: trends,
+	})
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+	"github.com/mjossany/Chirpy/internal/media"
+)
+
+const (
+	maxUploadSize     = 10 << 20
+	maxAttachments    = 4
+	maxAltTextLength  = 1000
+	mediaUploadField  = "file"
+	mediaUploadPrefix = "media/"
+	// maxMediaProcessing bounds the images being decoded and resized at
+	// once, each of which can take hundreds of megabytes; uploads beyond
+	// it are turned away.
+	maxMediaProcessing = 4
+	// Uploads that aren't attached to a chirp within unattachedMediaTTL,
+	// or whose chirps are deleted, are removed mediaReapBatch at a time
+	// every mediaReapInterval.
+	unattachedMediaTTL = 24 * time.Hour
+	mediaReapInterval  = time.Hour
+	mediaReapBatch     = 100
+)
+
+var mediaProcessing = make(chan struct{}, maxMediaProcessing)
+
+type Media struct {
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	BlurHash     string    `json:"blurhash"`
+}
+
+// Attachment is a media file as it hangs off a chirp.
+type Attachment struct {
+	Media
+	AltText string `json:"alt_text"`
+}
+
+func (cfg *apiConfig) mediaFromDatabase(dbMedia database.MediaFile) Media {
+	return Media{
+		ID:           dbMedia.ID,
+		CreatedAt:    dbMedia.CreatedAt,
+		ContentType:  dbMedia.ContentType,
+		SizeBytes:    dbMedia.SizeBytes,
+		Width:        dbMedia.Width,
+		Height:       dbMedia.Height,
+		URL:          cfg.blobs.URL(dbMedia.StorageKey),
+		ThumbnailURL: cfg.blobs.URL(dbMedia.ThumbnailKey),
+		BlurHash:     dbMedia.Blurhash,
+	}
+}
+
+func (cfg *apiConfig) handleMediaUpload(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	// Leave some room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+1<<20)
+	data, err := readUpload(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || errors.Is(err, errUploadTooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large", err)
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Couldn't read upload", err)
+		return
+	}
+
+	if !acquireMediaProcessing(w) {
+		return
+	}
+	img, err := media.Process(data)
+	<-mediaProcessing
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType):
+			respondWithError(w, http.StatusUnsupportedMediaType, "Only JPEG, PNG and GIF images are supported", err)
+		case errors.Is(err, media.ErrImageTooLarge):
+			respondWithError(w, http.StatusBadRequest, "Image dimensions are too large", err)
+		default:
+			respondWithError(w, 500, "Couldn't process image", err)
+		}
+		return
+	}
+
+	id := uuid.New()
+	storageKey := mediaUploadPrefix + id.String() + media.Extension(img.ContentType)
+	thumbnailKey := mediaUploadPrefix + id.String() + "_thumb.jpg"
+
+	err = cfg.blobs.Put(r.Context(), storageKey, img.Data, img.ContentType)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't store image", err)
+		return
+	}
+	err = cfg.blobs.Put(r.Context(), thumbnailKey, img.Thumbnail, "image/jpeg")
+	if err != nil {
+		cfg.deleteBlobs(storageKey)
+		respondWithError(w, 500, "Couldn't store image", err)
+		return
+	}
+
+	dbMedia, err := cfg.db.CreateMediaFile(r.Context(), database.CreateMediaFileParams{
+		ID:           id,
+		UserID:       userID,
+		ContentType:  img.ContentType,
+		SizeBytes:    int64(len(img.Data)),
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		StorageKey:   storageKey,
+		ThumbnailKey: thumbnailKey,
+		Blurhash:     img.BlurHash,
+	})
+	if err != nil {
+		cfg.deleteBlobs(storageKey, thumbnailKey)
+		respondWithError(w, 500, "Couldn't save media", err)
+		return
+	}
+
+	respondWithJSON(w, 201, cfg.mediaFromDatabase(dbMedia))
+}
+
+// acquireMediaProcessing takes a slot for processing an image, or
+// responds with a 503 and returns false if none is free. The caller
+// releases it by receiving from mediaProcessing.
+func acquireMediaProcessing(w http.ResponseWriter) bool {
+	select {
+	case mediaProcessing <- struct{}{}:
+		return true
+	default:
+		w.Header().Set("Retry-After", "5")
+		respondWithError(w, http.StatusServiceUnavailable, "Too many images being processed, try again shortly", nil)
+		return false
+	}
+}
+
+var errUploadTooLarge = errors.New("upload too large")
+
+// readUpload returns the contents of the "file" field of a multipart form.
+// It streams the form rather than parsing it whole, so nothing is spooled
+// to disk.
+func readUpload(r *http.Request) ([]byte, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			if err == io.EOF {
+				return nil, errors.New("missing " + mediaUploadField + " field")
+			}
+			return nil, err
+		}
+		if part.FormName() != mediaUploadField {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, maxUploadSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxUploadSize {
+			return nil, errUploadTooLarge
+		}
+		return data, nil
+	}
+}
+
+// deleteBlobs cleans up after a failed upload. It runs detached from the
+// request so a client hanging up doesn't leave the files behind.
+func (cfg *apiConfig) deleteBlobs(keys ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, key := range keys {
+		err := cfg.blobs.Delete(ctx, key)
+		if err != nil {
+			log.Printf("Couldn't delete blob %s: %s", key, err)
+		}
+	}
+}
+
+// reapUnattachedMedia runs for the life of the server, deleting uploads
+// that no chirp uses, along with their files.
+func (cfg *apiConfig) reapUnattachedMedia() {
+	ticker := time.NewTicker(mediaReapInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		err := cfg.deleteUnattachedMedia(context.Background())
+		if err != nil {
+			log.Printf("Couldn't delete unattached media: %s", err)
+		}
+	}
+}
+
+func (cfg *apiConfig) deleteUnattachedMedia(ctx context.Context) error {
+	for {
+		// Rows an attachment is being added to right now are locked, and
+		// skipped until the next pass.
+		deleted, err := cfg.db.DeleteUnattachedMediaFiles(ctx, database.DeleteUnattachedMediaFilesParams{
+			CreatedBefore: time.Now().UTC().Add(-unattachedMediaTTL),
+			MaxFiles:      mediaReapBatch,
+		})
+		if err != nil {
+			return err
+		}
+		for _, dbMedia := range deleted {
+			cfg.deleteBlobs(dbMedia.StorageKey, dbMedia.ThumbnailKey)
+		}
+		if len(deleted) < mediaReapBatch {
+			return nil
+		}
+	}
+}
+
+// attachMedia fills in the media attached to each chirp.
+func (cfg *apiConfig) attachMedia(ctx context.Context, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, c := range chirps {
+		ids[i] = c.ID
+	}
+	rows, err := cfg.db.ListChirpAttachments(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	attachments := make(map[uuid.UUID][]Attachment)
+	for _, row := range rows {
+		attachments[row.ChirpID] = append(attachments[row.ChirpID], Attachment{
+			Media:   cfg.mediaFromDatabase(row.MediaFile),
+			AltText: row.AltText,
+		})
+	}
+	for _, c := range chirps {
+		if a, ok := attachments[c.ID]; ok {
+			c.Media = a
+		}
+	}
+	return nil
+}
+
+type attachmentParams struct {
+	ID      uuid.UUID `json:"id"`
+	AltText string    `json:"alt_text"`
+}
+
+// validateAttachments checks a chirp's attachments before anything is
+// written: how many there are, their alt text, and that every one is an
+// upload of userID's. It returns a message for the client when they don't
+// pass.
+func (cfg *apiConfig) validateAttachments(ctx context.Context, userID uuid.UUID, attachments []attachmentParams) (string, error) {
+	if len(attachments) == 0 {
+		return "", nil
+	}
+	if len(attachments) > maxAttachments {
+		return "A chirp can have at most 4 attachments", nil
+	}
+
+	ids := make([]uuid.UUID, len(attachments))
+	seen := make(map[uuid.UUID]bool, len(attachments))
+	for i, a := range attachments {
+		if seen[a.ID] {
+			return "Each media file can only be attached once", nil
+		}
+		seen[a.ID] = true
+		if len([]rune(a.AltText)) > maxAltTextLength {
+			return "Alt text is too long", nil
+		}
+		ids[i] = a.ID
+	}
+
+	owned, err := cfg.db.GetUserMediaFiles(ctx, database.GetUserMediaFilesParams{
+		UserID: userID,
+		Ids:    ids,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(owned) != len(ids) {
+		return "Couldn't find media to attach", nil
+	}
+	return "", nil
+}
+
+func saveAttachments(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID, attachments []attachmentParams) error {
+	for i, a := range attachments {
+		err := qtx.AddChirpAttachment(ctx, database.AddChirpAttachmentParams{
+			ChirpID:  chirpID,
+			MediaID:  a.ID,
+			Position: int32(i),
+			AltText:  strings.TrimSpace(a.AltText),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// noDirectoryListing hides the directory indexes http.FileServer would
+// otherwise serve, so uploads can only be fetched by their exact key.
+func noDirectoryListing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
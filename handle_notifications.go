@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+// NotificationGroup folds every notification of one kind about one chirp
+// (or, for follows, about the user) into a single inbox item.
+type NotificationGroup struct {
+	ID             uuid.UUID     `json:"id"`
+	Kind           string        `json:"kind"`
+	ChirpID        uuid.NullUUID `json:"chirp_id"`
+	Summary        string        `json:"summary"`
+	ActorCount     int64         `json:"actor_count"`
+	RecentActorIDs []uuid.UUID   `json:"recent_actor_ids"`
+	Unread         bool          `json:"unread"`
+	LatestAt       time.Time     `json:"latest_at"`
+}
+
+func (cfg *apiConfig) handleNotificationList(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	page, err := parseFeedPageRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.keyset()
+	rows, err := cfg.db.ListNotificationGroups(r.Context(), database.ListNotificationGroupsParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get notifications", err)
+		return
+	}
+
+	unreadCount, err := cfg.db.CountUnreadNotificationGroups(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't count unread notifications", err)
+		return
+	}
+
+	rows, next, _ := paginate(page, rows, func(row database.ListNotificationGroupsRow) (time.Time, uuid.UUID) {
+		return row.LatestAt, row.GroupID
+	})
+
+	groups := make([]NotificationGroup, len(rows))
+	for i, row := range rows {
+		groups[i] = NotificationGroup{
+			ID:             row.GroupID,
+			Kind:           row.Kind,
+			ChirpID:        row.ChirpID,
+			Summary:        notificationSummary(row.Kind, row.ActorCount),
+			ActorCount:     row.ActorCount,
+			RecentActorIDs: row.RecentActorIds,
+			Unread:         row.UnreadCount > 0,
+			LatestAt:       row.LatestAt,
+		}
+	}
+
+	type response struct {
+		Notifications []NotificationGroup `json:"notifications"`
+		UnreadCount   int64               `json:"unread_count"`
+		NextCursor    *string             `json:"next_cursor"`
+	}
+
+	respondWithJSON(w, 200, response{
+		Notifications: groups,
+		UnreadCount:   unreadCount,
+		NextCursor:    next,
+	})
+}
+
+func (cfg *apiConfig) handleNotificationUnreadCount(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	unreadCount, err := cfg.db.CountUnreadNotificationGroups(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't count unread notifications", err)
+		return
+	}
+
+	type response struct {
+		UnreadCount int64 `json:"unread_count"`
+	}
+
+	respondWithJSON(w, 200, response{
+		UnreadCount: unreadCount,
+	})
+}
+
+func (cfg *apiConfig) handleNotificationRead(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	type parameters struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	// Without ids the whole inbox is marked read.
+	if len(params.IDs) == 0 {
+		err = cfg.db.MarkAllNotificationsRead(r.Context(), userID)
+	} else {
+		err = cfg.db.MarkNotificationGroupsRead(r.Context(), database.MarkNotificationGroupsReadParams{
+			UserID:   userID,
+			GroupIds: params.IDs,
+		})
+	}
+	if err != nil {
+		respondWithError(w, 500, "Couldn't mark notifications read", err)
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
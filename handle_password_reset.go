@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+	chirpymail "github.com/mjossany/Chirpy/internal/mail"
+)
+
+const (
+	passwordResetExpiresIn = 30 * time.Minute
+	passwordResetTimeout   = time.Minute
+	// passwordResetCooldown is how long a reset has to be outstanding
+	// before asking again mails another, so the endpoint can't be used to
+	// flood an inbox.
+	passwordResetCooldown = 5 * time.Minute
+	// maxPasswordResetSends bounds the resets being looked up and mailed
+	// at once; requests beyond it are dropped.
+	maxPasswordResetSends = 16
+)
+
+var passwordResetSends = make(chan struct{}, maxPasswordResetSends)
+
+// handleForgotPassword mails a reset token to the address if it belongs
+// to an account. The lookup and the email happen after the response has
+// gone out, so the reply is the same, and just as quick, whether or not
+// the account exists.
+func (cfg *apiConfig) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	address, err := mail.ParseAddress(strings.TrimSpace(params.Email))
+	if err != nil || address.Name != "" {
+		respondWithError(w, 400, "Invalid email address", err)
+		return
+	}
+
+	select {
+	case passwordResetSends <- struct{}{}:
+	default:
+		log.Printf("Too many password resets in flight, dropping one")
+		respondWithJSON(w, 204, nil)
+		return
+	}
+
+	go func() {
+		defer func() { <-passwordResetSends }()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), passwordResetTimeout)
+		defer cancel()
+		err := cfg.sendPasswordReset(ctx, address.Address)
+		if err != nil {
+			log.Printf("Couldn't send password reset: %s", err)
+		}
+	}()
+
+	respondWithJSON(w, 204, nil)
+}
+
+// sendPasswordReset replaces any reset already outstanding for the
+// account with email and mails the new token, unless the outstanding one
+// is younger than passwordResetCooldown. An unknown email is not an
+// error.
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, email string) error {
+	dbUser, err := cfg.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	replaced, err := cfg.db.ReplacePasswordReset(ctx, database.ReplacePasswordResetParams{
+		TokenHash:       auth.HashToken(token),
+		UserID:          dbUser.ID,
+		ExpiresAt:       time.Now().UTC().Add(passwordResetExpiresIn),
+		CooldownSeconds: int32(passwordResetCooldown.Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+	if replaced == 0 {
+		return nil
+	}
+
+	return cfg.mailer.Send(ctx, chirpymail.Message{
+		To:      dbUser.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for the Chirpy account "+
+			"with this address. If it was you, choose a new one within 30 minutes by "+
+			"sending this token and your new password to POST /api/password/reset:\n\n%s\n\n"+
+			"If it wasn't, ignore this email and your password won't change.", token),
+	})
+}
+
+// handleResetPassword sets a new password using a token from
+// handleForgotPassword, then signs the account out everywhere by revoking
+// its refresh tokens.
+func (cfg *apiConfig) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	if params.Password == "" {
+		respondWithError(w, 400, "Password can't be blank", nil)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't hash password", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't reset password", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	reset, err := qtx.ConsumePasswordReset(r.Context(), auth.HashToken(params.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 400, "Invalid or expired token", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't reset password", err)
+		return
+	}
+	if time.Now().UTC().After(reset.ExpiresAt) {
+		// Still commit, so the expired token is cleared out.
+		tx.Commit()
+		respondWithError(w, 400, "Invalid or expired token", nil)
+		return
+	}
+
+	_, err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		ID:             reset.UserID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't reset password", err)
+		return
+	}
+
+	err = qtx.RevokeUserRefreshTokens(r.Context(), reset.UserID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't reset password", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't reset password", err)
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+const (
+	maxPinnedChirps    = 1
+	maxPinnedChirpsRed = 3
+)
+
+// pinLimit is how many chirps dbUser may have pinned. Pins beyond it,
+// left over from a lapsed Chirpy Red subscription, are listed like any
+// other chirp.
+func pinLimit(dbUser database.User) int {
+	if dbUser.IsChirpyRed {
+		return maxPinnedChirpsRed
+	}
+	return maxPinnedChirps
+}
+
+func (cfg *apiConfig) handlePinChirp(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Couldn't find chirp", nil)
+		return
+	}
+
+	if dbChirp.UserID != userID {
+		respondWithError(w, 403, "Unauthorized action", nil)
+		return
+	}
+
+	if dbChirp.RechirpOf.Valid {
+		respondWithError(w, 400, "Rechirps can't be pinned", nil)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't find user", err)
+		return
+	}
+	limit := pinLimit(dbUser)
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't pin chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Locking the user's row serialises concurrent pins, so two requests
+	// can't both see room for one more.
+	err = qtx.LockUserPins(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't pin chirp", err)
+		return
+	}
+
+	pinned, err := qtx.PinChirp(r.Context(), database.PinChirpParams{
+		UserID:  userID,
+		ChirpID: chirpUUID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't pin chirp", err)
+		return
+	}
+	if pinned > 0 {
+		count, err := qtx.CountPinnedChirps(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't pin chirp", err)
+			return
+		}
+		if count > int64(limit) {
+			msg := "You can only pin 1 chirp; unpin it first"
+			if limit > 1 {
+				msg = fmt.Sprintf("You can only pin %d chirps; unpin one first", limit)
+			}
+			respondWithError(w, 409, msg, nil)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't pin chirp", err)
+		return
+	}
+
+	chirp := chirpFromDatabase(dbChirp)
+	chirp.Pinned = true
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, &chirp)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 200, chirp)
+}
+
+func (cfg *apiConfig) handleUnpinChirp(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+
+	if dbChirp.UserID != userID {
+		respondWithError(w, 403, "Unauthorized action", nil)
+		return
+	}
+
+	err = cfg.db.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		UserID:  userID,
+		ChirpID: chirpUUID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't unpin chirp", err)
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+// handleUserChirps lists a user's chirps newest first. The first page
+// opens with their pinned chirps, which are left out of the rest of the
+// listing so they don't show up twice. Pins count towards the page size,
+// though the first page always has room for at least one other chirp.
+func (cfg *apiConfig) handleUserChirps(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID", err)
+		return
+	}
+
+	page, err := parseFeedPageRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "User can't be found", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find user", err)
+		return
+	}
+
+	var responseChirps []Chirp
+	if page.Cursor == nil {
+		pins, err := cfg.db.ListPinnedChirps(r.Context(), database.ListPinnedChirpsParams{
+			UserID:  userUUID,
+			MaxPins: int32(pinLimit(dbUser)),
+		})
+		if err != nil {
+			respondWithError(w, 500, "Couldn't get pinned chirps", err)
+			return
+		}
+		for _, pin := range pins {
+			chirp := chirpFromDatabase(pin.Chirp)
+			chirp.Pinned = true
+			responseChirps = append(responseChirps, chirp)
+		}
+		page.Limit = max(page.Limit-len(pins), 1)
+	}
+
+	cursorCreatedAt, cursorID := page.keyset()
+	dbChirps, err := cfg.db.ListUserChirps(r.Context(), database.ListUserChirpsParams{
+		UserID:          userUUID,
+		MaxPins:         int32(pinLimit(dbUser)),
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get chirps", err)
+		return
+	}
+
+	dbChirps, next, _ := paginate(page, dbChirps, func(c database.Chirp) (time.Time, uuid.UUID) {
+		return c.CreatedAt, c.ID
+	})
+	for _, dbChirp := range dbChirps {
+		responseChirps = append(responseChirps, chirpFromDatabase(dbChirp))
+	}
+	if responseChirps == nil {
+		responseChirps = []Chirp{}
+	}
+
+	err = cfg.hydrateChirps(r.Context(), cfg.viewerID(r), chirpRefs(responseChirps)...)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 200, chirpPage{
+		Chirps:     responseChirps,
+		NextCursor: next,
+	})
+}
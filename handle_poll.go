@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+// Poll limits. Chirpy Red users get more options and can keep a poll
+// open for longer.
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionsRed   = 6
+	maxPollOptionLength = 25
+	minPollDuration     = 5 * time.Minute
+	maxPollDuration     = 7 * 24 * time.Hour
+	maxPollDurationRed  = 30 * 24 * time.Hour
+)
+
+// Poll is the poll attached to a chirp. Votes and TotalVotes stay hidden
+// until the viewer has voted or the poll has closed, so early results
+// can't sway anyone.
+type Poll struct {
+	ClosesAt    time.Time    `json:"closes_at"`
+	Closed      bool         `json:"closed"`
+	Options     []PollOption `json:"options"`
+	TotalVotes  *int64       `json:"total_votes,omitempty"`
+	VotedOption *int32       `json:"voted_option,omitempty"`
+}
+
+type PollOption struct {
+	Position int32  `json:"position"`
+	Label    string `json:"label"`
+	Votes    *int64 `json:"votes,omitempty"`
+}
+
+type pollParams struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+// validatePoll checks a new poll against the limits for the author's plan
+// and returns a message for the client when it doesn't pass. Option labels
+// are trimmed in place.
+func validatePoll(poll *pollParams, isChirpyRed bool, now time.Time) string {
+	maxOptions, maxDuration := maxPollOptions, maxPollDuration
+	if isChirpyRed {
+		maxOptions, maxDuration = maxPollOptionsRed, maxPollDurationRed
+	}
+
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxOptions {
+		if isChirpyRed {
+			return "A poll needs 2 to 6 options"
+		}
+		return "A poll needs 2 to 4 options"
+	}
+
+	seen := make(map[string]bool, len(poll.Options))
+	for i, option := range poll.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return "Poll options can't be blank"
+		}
+		if len([]rune(option)) > maxPollOptionLength {
+			return "Poll option is too long"
+		}
+		if seen[strings.ToLower(option)] {
+			return "Poll options must be different"
+		}
+		seen[strings.ToLower(option)] = true
+		poll.Options[i] = option
+	}
+
+	duration := poll.ClosesAt.Sub(now)
+	if duration < minPollDuration {
+		return "Poll must stay open for at least 5 minutes"
+	}
+	if duration > maxDuration {
+		if isChirpyRed {
+			return "Poll can stay open for at most 30 days"
+		}
+		return "Poll can stay open for at most 7 days"
+	}
+	return ""
+}
+
+func savePoll(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID, poll *pollParams) error {
+	err := qtx.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirpID,
+		ClosesAt: poll.ClosesAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	for i, option := range poll.Options {
+		err = qtx.AddPollOption(ctx, database.AddPollOptionParams{
+			ChirpID:  chirpID,
+			Position: int32(i),
+			Label:    option,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachPolls fills in the polls on chirps that have one, with the
+// viewer's vote and, where they may see them, the tallies.
+func (cfg *apiConfig) attachPolls(ctx context.Context, viewer uuid.NullUUID, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, c := range chirps {
+		ids[i] = c.ID
+	}
+	dbPolls, err := cfg.db.ListPolls(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if len(dbPolls) == 0 {
+		return nil
+	}
+
+	pollIDs := make([]uuid.UUID, len(dbPolls))
+	for i, p := range dbPolls {
+		pollIDs[i] = p.ChirpID
+	}
+	options, err := cfg.db.ListPollOptions(ctx, pollIDs)
+	if err != nil {
+		return err
+	}
+	votes := make(map[uuid.UUID]int32)
+	if viewer.Valid {
+		rows, err := cfg.db.ListPollVotes(ctx, database.ListPollVotesParams{
+			UserID:   viewer.UUID,
+			ChirpIds: pollIDs,
+		})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			votes[row.ChirpID] = row.Position
+		}
+	}
+
+	now := time.Now().UTC()
+	polls := make(map[uuid.UUID]*Poll, len(dbPolls))
+	for _, p := range dbPolls {
+		poll := &Poll{
+			ClosesAt: p.ClosesAt,
+			Closed:   !now.Before(p.ClosesAt),
+			Options:  []PollOption{},
+		}
+		if position, ok := votes[p.ChirpID]; ok {
+			poll.VotedOption = &position
+		}
+		polls[p.ChirpID] = poll
+	}
+
+	for _, row := range options {
+		poll := polls[row.ChirpID]
+		option := PollOption{
+			Position: row.Position,
+			Label:    row.Label,
+		}
+		if poll.Closed || poll.VotedOption != nil {
+			count := row.VoteCount
+			option.Votes = &count
+			if poll.TotalVotes == nil {
+				poll.TotalVotes = new(int64)
+			}
+			*poll.TotalVotes += count
+		}
+		poll.Options = append(poll.Options, option)
+	}
+
+	for _, c := range chirps {
+		if poll, ok := polls[c.ID]; ok {
+			c.Poll = poll
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlePollVote(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	type parameters struct {
+		Option *int32 `json:"option"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+	if params.Option == nil {
+		respondWithError(w, 400, "Missing option", nil)
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Couldn't find chirp", nil)
+		return
+	}
+
+	dbPoll, err := cfg.db.GetPoll(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find poll", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find poll", err)
+		return
+	}
+	if !time.Now().UTC().Before(dbPoll.ClosesAt) {
+		respondWithError(w, 403, "Poll has closed", nil)
+		return
+	}
+
+	// The (chirp_id, position) foreign key rejects options the poll
+	// doesn't have, and the primary key a second vote.
+	cast, err := cfg.db.CastPollVote(r.Context(), database.CastPollVoteParams{
+		ChirpID:  chirpUUID,
+		UserID:   userID,
+		Position: *params.Option,
+	})
+	if err != nil {
+		if isForeignKeyViolation(err, "poll_votes_chirp_id_position_fkey") {
+			respondWithError(w, 400, "Invalid poll option", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't record vote", err)
+		return
+	}
+	if cast == 0 {
+		respondWithError(w, 409, "You have already voted in this poll", nil)
+		return
+	}
+
+	chirp := chirpFromDatabase(dbChirp)
+	err = cfg.attachPolls(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, []*Chirp{&chirp})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load poll", err)
+		return
+	}
+
+	respondWithJSON(w, 200, chirp.Poll)
+}
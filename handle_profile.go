@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/database"
+	"github.com/mjossany/Chirpy/internal/entities"
+)
+
+// Profile is the public view of a user. It must never carry anything
+// private, such as the email address.
+type Profile struct {
+	ID             uuid.UUID         `json:"id"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Handle         string            `json:"handle,omitempty"`
+	DisplayName    string            `json:"display_name"`
+	Bio            string            `json:"bio"`
+	Website        string            `json:"website"`
+	AvatarURLs     map[string]string `json:"avatar_urls,omitempty"`
+	BannerURLs     map[string]string `json:"banner_urls,omitempty"`
+	IsChirpyRed    bool              `json:"is_chirpy_red"`
+	ChirpCount     int64             `json:"chirp_count"`
+	FollowerCount  int64             `json:"follower_count"`
+	FollowingCount int64             `json:"following_count"`
+}
+
+// lookupUser finds a user by UUID or, failing that, by handle with or
+// without its leading '@'.
+func (cfg *apiConfig) lookupUser(r *http.Request, handleOrID string) (database.User, error) {
+	if id, err := uuid.Parse(handleOrID); err == nil {
+		return cfg.db.GetUserByID(r.Context(), id)
+	}
+	handle := entities.NormalizeHandle(handleOrID)
+	if !entities.ValidHandle(handle) {
+		return database.User{}, sql.ErrNoRows
+	}
+	return cfg.db.GetUserByHandle(r.Context(), handle)
+}
+
+func (cfg *apiConfig) handleUserProfile(w http.ResponseWriter, r *http.Request) {
+	dbUser, err := cfg.lookupUser(r, r.PathValue("handleOrID"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "User can't be found", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find user", err)
+		return
+	}
+
+	row, err := cfg.db.GetUserProfile(r.Context(), dbUser.ID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't find user", err)
+		return
+	}
+
+	respondWithJSON(w, 200, Profile{
+		ID:             row.User.ID,
+		CreatedAt:      row.User.CreatedAt,
+		Handle:         row.User.Handle.String,
+		DisplayName:    row.User.DisplayName,
+		Bio:            row.User.Bio,
+		Website:        row.User.Website,
+		AvatarURLs:     avatarImage.urls(cfg, row.User.AvatarKey),
+		BannerURLs:     bannerImage.urls(cfg, row.User.BannerKey),
+		IsChirpyRed:    row.User.IsChirpyRed,
+		ChirpCount:     row.ChirpCount,
+		FollowerCount:  row.FollowerCount,
+		FollowingCount: row.FollowingCount,
+	})
+}
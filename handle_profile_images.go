@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+	"github.com/mjossany/Chirpy/internal/media"
+)
+
+// profileImage describes one of the images on a profile: the sizes it is
+// produced at and how its key is swapped on the user row.
+type profileImage struct {
+	name  string
+	sizes []media.Size
+	// set stores key as the user's image and returns the key it replaced.
+	set func(ctx context.Context, q *database.Queries, userID uuid.UUID, key sql.NullString) (sql.NullString, error)
+}
+
+var avatarImage = profileImage{
+	name: "avatar",
+	sizes: []media.Size{
+		{Name: "large", Width: 400, Height: 400},
+		{Name: "medium", Width: 128, Height: 128},
+		{Name: "small", Width: 48, Height: 48},
+	},
+	set: func(ctx context.Context, q *database.Queries, userID uuid.UUID, key sql.NullString) (sql.NullString, error) {
+		return q.SetUserAvatar(ctx, database.SetUserAvatarParams{AvatarKey: key, ID: userID})
+	},
+}
+
+var bannerImage = profileImage{
+	name: "banner",
+	sizes: []media.Size{
+		{Name: "large", Width: 1500, Height: 500},
+		{Name: "small", Width: 600, Height: 200},
+	},
+	set: func(ctx context.Context, q *database.Queries, userID uuid.UUID, key sql.NullString) (sql.NullString, error) {
+		return q.SetUserBanner(ctx, database.SetUserBannerParams{BannerKey: key, ID: userID})
+	},
+}
+
+func (img profileImage) variantKey(key, size string) string {
+	return key + "_" + size + ".jpg"
+}
+
+// urls maps each size of the image stored under key to where it can be
+// fetched, or returns nil when there is no image.
+func (img profileImage) urls(cfg *apiConfig, key sql.NullString) map[string]string {
+	if !key.Valid {
+		return nil
+	}
+	urls := make(map[string]string, len(img.sizes))
+	for _, size := range img.sizes {
+		urls[size.Name] = cfg.blobs.URL(img.variantKey(key.String, size.Name))
+	}
+	return urls
+}
+
+// deleteImage removes every size of the image stored under key.
+func (cfg *apiConfig) deleteImage(img profileImage, key sql.NullString) {
+	if !key.Valid {
+		return
+	}
+	keys := make([]string, len(img.sizes))
+	for i, size := range img.sizes {
+		keys[i] = img.variantKey(key.String, size.Name)
+	}
+	cfg.deleteBlobs(keys...)
+}
+
+func (cfg *apiConfig) handleAvatarUpload(w http.ResponseWriter, r *http.Request) {
+	cfg.uploadProfileImage(w, r, avatarImage)
+}
+
+func (cfg *apiConfig) handleAvatarDelete(w http.ResponseWriter, r *http.Request) {
+	cfg.deleteProfileImage(w, r, avatarImage)
+}
+
+func (cfg *apiConfig) handleBannerUpload(w http.ResponseWriter, r *http.Request) {
+	cfg.uploadProfileImage(w, r, bannerImage)
+}
+
+func (cfg *apiConfig) handleBannerDelete(w http.ResponseWriter, r *http.Request) {
+	cfg.deleteProfileImage(w, r, bannerImage)
+}
+
+// uploadProfileImage crops an upload to the image's shape, stores every
+// size under a fresh key and points the user at it. The image it replaces
+// is deleted once nothing refers to it any more.
+func (cfg *apiConfig) uploadProfileImage(w http.ResponseWriter, r *http.Request, img profileImage) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+1<<20)
+	data, err := readUpload(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || errors.Is(err, errUploadTooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large", err)
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Couldn't read upload", err)
+		return
+	}
+
+	if !acquireMediaProcessing(w) {
+		return
+	}
+	variants, err := media.Crop(data, img.sizes...)
+	<-mediaProcessing
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType):
+			respondWithError(w, http.StatusUnsupportedMediaType, "Only JPEG, PNG and GIF images are supported", err)
+		case errors.Is(err, media.ErrImageTooLarge):
+			respondWithError(w, http.StatusBadRequest, "Image dimensions are too large", err)
+		default:
+			respondWithError(w, 500, "Couldn't process image", err)
+		}
+		return
+	}
+
+	// A fresh key for every upload means caches never serve the old image
+	// under the new URL.
+	key := sql.NullString{String: img.name + "s/" + userID.String() + "/" + uuid.NewString(), Valid: true}
+	for _, v := range variants {
+		err = cfg.blobs.Put(r.Context(), img.variantKey(key.String, v.Name), v.Data, "image/jpeg")
+		if err != nil {
+			cfg.deleteImage(img, key)
+			respondWithError(w, 500, "Couldn't store image", err)
+			return
+		}
+	}
+
+	previous, err := img.set(r.Context(), cfg.db, userID, key)
+	if err != nil {
+		cfg.deleteImage(img, key)
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find user", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't update "+img.name, err)
+		return
+	}
+	cfg.deleteImage(img, previous)
+
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't find user", err)
+		return
+	}
+
+	respondWithJSON(w, 200, cfg.userFromDatabase(dbUser))
+}
+
+func (cfg *apiConfig) deleteProfileImage(w http.ResponseWriter, r *http.Request, img profileImage) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	previous, err := img.set(r.Context(), cfg.db, userID, sql.NullString{})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find user", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't remove "+img.name, err)
+		return
+	}
+	cfg.deleteImage(img, previous)
+
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleRechirp(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	original, err := cfg.getShareableChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't rechirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	dbChirp, err := qtx.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:    userID,
+		RechirpOf: uuid.NullUUID{UUID: original.ID, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 409, "Chirp already rechirped", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't rechirp", err)
+		return
+	}
+
+	err = qtx.AdjustChirpShareCounts(r.Context(), database.AdjustChirpShareCountsParams{
+		RechirpDelta: 1,
+		ID:           original.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't update rechirp count", err)
+		return
+	}
+
+	notifications, err := notify(r.Context(), qtx, original.UserID, userID, notificationKindRechirp, uuid.NullUUID{UUID: original.ID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't create notification", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't rechirp", err)
+		return
+	}
+
+	cfg.publishChirp(r.Context(), dbChirp)
+	cfg.publishNotifications(notifications...)
+
+	chirp := chirpFromDatabase(dbChirp)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, &chirp)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 201, chirp)
+}
+
+func (cfg *apiConfig) handleUndoRechirp(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id", err)
+		return
+	}
+
+	// The path may name the rechirp itself rather than the original,
+	// just as when rechirping.
+	original, err := cfg.getShareableChirp(r.Context(), chirpUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find chirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find chirp", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't undo rechirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	rechirp, err := qtx.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:    userID,
+		RechirpOf: uuid.NullUUID{UUID: original.ID, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Couldn't find rechirp", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't undo rechirp", err)
+		return
+	}
+
+	err = qtx.AdjustChirpShareCounts(r.Context(), database.AdjustChirpShareCountsParams{
+		RechirpDelta: -1,
+		ID:           original.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't update rechirp count", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't undo rechirp", err)
+		return
+	}
+
+	cfg.publishChirpDeleted(rechirp)
+
+	respondWithJSON(w, 204, nil)
+}
+
+// getShareableChirp returns the chirp a rechirp or quote of id should point
+// at: the chirp itself, or the original when id is itself a rechirp.
+// Deleted chirps come back as sql.ErrNoRows.
+func (cfg *apiConfig) getShareableChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	dbChirp, err := cfg.db.GetChirp(ctx, id)
+	if err != nil {
+		return dbChirp, err
+	}
+	if dbChirp.RechirpOf.Valid {
+		dbChirp, err = cfg.db.GetChirp(ctx, dbChirp.RechirpOf.UUID)
+		if err != nil {
+			return dbChirp, err
+		}
+	}
+	if dbChirp.DeletedAt.Valid {
+		return dbChirp, sql.ErrNoRows
+	}
+	return dbChirp, nil
+}
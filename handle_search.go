@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/search"
+)
+
+// maxSearchOffset stops clients paging arbitrarily deep into results,
+// which gets slower the further they go.
+const maxSearchOffset = 1000
+
+// searchCursor is the position of the next page of search results.
+// Results are ordered by relevance, which has no stable keyset, so unlike
+// pageCursor it is a plain offset.
+type searchCursor struct {
+	Offset int `json:"o"`
+}
+
+type SearchResult struct {
+	Chirp   Chirp   `json:"chirp"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+func (cfg *apiConfig) handleSearchChirps(w http.ResponseWriter, r *http.Request) {
+	q, err := search.ParseQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	limit := defaultPageSize
+	if param := r.URL.Query().Get("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "limit must be a positive integer", err)
+			return
+		}
+		limit = min(n, maxPageSize)
+	}
+
+	offset := 0
+	if param := r.URL.Query().Get("cursor"); param != "" {
+		c, err := decodeSearchCursor(param)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid cursor", err)
+			return
+		}
+		offset = c.Offset
+	}
+
+	results, err := cfg.searcher.Search(r.Context(), q, limit+1, offset)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't search chirps", err)
+		return
+	}
+
+	var next *string
+	if len(results) > limit {
+		results = results[:limit]
+		if offset+limit <= maxSearchOffset {
+			s := encodeSearchCursor(searchCursor{Offset: offset + limit})
+			next = &s
+		}
+	}
+
+	ids := make([]uuid.UUID, len(results))
+	for i, result := range results {
+		ids[i] = result.ChirpID
+	}
+	dbChirps, err := cfg.db.GetChirpsByIDs(r.Context(), ids)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get chirps", err)
+		return
+	}
+	chirpsByID := make(map[uuid.UUID]Chirp, len(dbChirps))
+	for _, dbChirp := range dbChirps {
+		chirpsByID[dbChirp.ID] = chirpFromDatabase(dbChirp)
+	}
+
+	// A chirp deleted between the search and the fetch just drops out.
+	searchResults := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		chirp, ok := chirpsByID[result.ChirpID]
+		if !ok || chirp.Deleted {
+			continue
+		}
+		searchResults = append(searchResults, SearchResult{
+			Chirp:   chirp,
+			Rank:    result.Rank,
+			Snippet: result.Snippet,
+		})
+	}
+
+	refs := make([]*Chirp, len(searchResults))
+	for i := range searchResults {
+		refs[i] = &searchResults[i].Chirp
+	}
+	err = cfg.hydrateChirps(r.Context(), cfg.viewerID(r), refs...)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	type response struct {
+		Results    []SearchResult `json:"results"`
+		NextCursor *string        `json:"next_cursor"`
+	}
+
+	respondWithJSON(w, 200, response{
+		Results:    searchResults,
+		NextCursor: next,
+	})
+}
+
+func encodeSearchCursor(c searchCursor) string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeSearchCursor(s string) (searchCursor, error) {
+	c := searchCursor{}
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(dat, &c)
+	if err != nil {
+		return c, err
+	}
+	if c.Offset < 0 || c.Offset > maxSearchOffset {
+		return c, errors.New("cursor out of range")
+	}
+	return c, nil
+}
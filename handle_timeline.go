@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	page.Desc = true
+
+	cursorCreatedAt, cursorID := page.keyset()
+
+	var dbChirps []database.Chirp
+	if page.ascending() {
+		dbChirps, err = cfg.db.ListTimelineAfter(r.Context(), database.ListTimelineAfterParams{
+			FollowerID:      userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        page.fetchSize(),
+		})
+	} else {
+		dbChirps, err = cfg.db.ListTimelineBefore(r.Context(), database.ListTimelineBeforeParams{
+			FollowerID:      userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        page.fetchSize(),
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get timeline", err)
+		return
+	}
+
+	dbChirps, next, prev := paginate(page, dbChirps, func(c database.Chirp) (time.Time, uuid.UUID) {
+		return c.CreatedAt, c.ID
+	})
+
+	responseChirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		responseChirps[i] = chirpFromDatabase(dbChirp)
+	}
+
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirpRefs(responseChirps)...)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 200, chirpPage{
+		Chirps:     responseChirps,
+		NextCursor: next,
+		PrevCursor: prev,
+	})
+}
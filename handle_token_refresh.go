@@ -1,15 +1,19 @@
 package main
 
 import (
+	"database/sql"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
 )
 
 func (cfg *apiConfig) handleTokenRefresh(w http.ResponseWriter, r *http.Request) {
 	type response struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	refreshToken, err := auth.GetBearerToken(r.Header)
@@ -18,19 +22,75 @@ func (cfg *apiConfig) handleTokenRefresh(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	dbUser, err := cfg.db.GetUserFromRefreshToken(r.Context(), refreshToken)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
 	if err != nil {
-		respondWithError(w, 401, "Couldn't validate token", err)
+		respondWithError(w, 500, "Couldn't refresh token", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
 
-	accessToken, err := auth.MakeJWT(dbUser.ID, cfg.jwtSecret, time.Hour)
+	// Locking the row makes two refreshes racing with the same token take
+	// turns, so the second one is seen as a reuse.
+	dbRefreshToken, err := qtx.GetRefreshTokenForUpdate(r.Context(), auth.HashToken(refreshToken))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 401, "Couldn't validate token", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't refresh token", err)
+		return
+	}
+	if dbRefreshToken.RevokedAt.Valid || time.Now().UTC().After(dbRefreshToken.ExpiresAt) {
+		respondWithError(w, 401, "Couldn't validate token", nil)
+		return
+	}
+	if dbRefreshToken.ReplacedBy.Valid {
+		// The token was already swapped for a new one, so whoever holds
+		// this copy may not be its owner. End the whole session.
+		err = qtx.RevokeRefreshTokenFamily(r.Context(), dbRefreshToken.FamilyID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't refresh token", err)
+			return
+		}
+		err = tx.Commit()
+		if err != nil {
+			respondWithError(w, 500, "Couldn't refresh token", err)
+			return
+		}
+		log.Printf("Refresh token reused, revoked family %s of user %s", dbRefreshToken.FamilyID, dbRefreshToken.UserID)
+		respondWithError(w, 401, "Couldn't validate token", nil)
+		return
+	}
+
+	newRefreshToken, err := issueRefreshToken(r.Context(), qtx, dbRefreshToken.UserID, dbRefreshToken.FamilyID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't refresh token", err)
+		return
+	}
+	err = qtx.MarkRefreshTokenReplaced(r.Context(), database.MarkRefreshTokenReplacedParams{
+		TokenHash:  dbRefreshToken.TokenHash,
+		ReplacedBy: sql.NullString{String: auth.HashToken(newRefreshToken), Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't refresh token", err)
+		return
+	}
+
+	accessToken, err := auth.MakeJWT(dbRefreshToken.UserID, cfg.jwtSecret, time.Hour)
 	if err != nil {
 		respondWithError(w, 500, "Couldn't validate token", err)
 		return
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't refresh token", err)
+		return
+	}
+
 	respondWithJSON(w, 200, response{
-		Token: accessToken,
+		Token:        accessToken,
+		RefreshToken: newRefreshToken,
 	})
 }
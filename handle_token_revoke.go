@@ -13,7 +13,7 @@ func (cfg *apiConfig) handleTokenRevoke(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = cfg.db.RevokeRefreshToken(r.Context(), authorization)
+	_, err = cfg.db.RevokeRefreshToken(r.Context(), auth.HashToken(authorization))
 	if err != nil {
 		respondWithError(w, 500, "Couldn't revoke token", err)
 		return
@@ -0,0 +1,527 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+const (
+	totpIssuer        = "Chirpy"
+	mfaTokenExpiresIn = 5 * time.Minute
+	recoveryCodeCount = 10
+	// After maxMFAAttempts wrong codes in a row, second factors are
+	// refused for mfaLockout, which keeps six-digit codes out of reach of
+	// guessing.
+	maxMFAAttempts = 5
+	mfaLockout     = 15 * time.Minute
+)
+
+var errMFALocked = errors.New("too many failed two-factor attempts")
+
+// mfaChallenge is what a password login returns instead of tokens when the
+// account has two-factor authentication on. MFAToken is exchanged, along
+// with a code, at /api/login/mfa.
+type mfaChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// verifySecondFactor accepts either a TOTP code that hasn't been used yet
+// or one of the user's unused recovery codes, using it up. Every attempt
+// is counted towards the lockout before the code is checked, so parallel
+// requests can't get past the limit; a right code clears the count. A
+// wrong code reports false without an error.
+func (cfg *apiConfig) verifySecondFactor(ctx context.Context, totp database.UserTotp, code string) (bool, error) {
+	now := time.Now().UTC()
+	totp, err := cfg.db.ClaimMFAAttempt(ctx, database.ClaimMFAAttemptParams{
+		MaxAttempts: maxMFAAttempts,
+		LockedUntil: now.Add(mfaLockout),
+		UserID:      totp.UserID,
+		Now:         now,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, errMFALocked
+		}
+		return false, err
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := auth.ValidateTOTP(totp.Secret, code, now); ok {
+		// A code that was right but has already been used counts as a
+		// wrong one.
+		used, err := cfg.db.UseTOTPStep(ctx, database.UseTOTPStepParams{
+			UserID:   totp.UserID,
+			LastStep: step,
+		})
+		return used > 0, err
+	}
+	if code == "" {
+		return false, nil
+	}
+	used, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   totp.UserID,
+		CodeHash: auth.HashRecoveryCode(totp.UserID, code, cfg.jwtSecret),
+	})
+	if err != nil || used == 0 {
+		return false, err
+	}
+	return true, cfg.db.ResetMFAFailures(ctx, totp.UserID)
+}
+
+// replaceRecoveryCodes throws away any recovery codes the user has left
+// and returns a new set, which is the only time they are seen in full.
+func (cfg *apiConfig) replaceRecoveryCodes(ctx context.Context, qtx *database.Queries, userID uuid.UUID) ([]string, error) {
+	err := qtx.DeleteUserRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		err = qtx.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashRecoveryCode(userID, code, cfg.jwtSecret),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// handleTwoFactorStatus tells the user whether two-factor authentication
+// is on and how many recovery codes they have left.
+func (cfg *apiConfig) handleTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Enabled                bool  `json:"enabled"`
+		RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+	}
+
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, 500, "Couldn't get two-factor status", err)
+		return
+	}
+	if err == sql.ErrNoRows || !totp.EnabledAt.Valid {
+		respondWithJSON(w, 200, response{})
+		return
+	}
+
+	remaining, err := cfg.db.CountRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get two-factor status", err)
+		return
+	}
+
+	respondWithJSON(w, 200, response{
+		Enabled:                true,
+		RecoveryCodesRemaining: remaining,
+	})
+}
+
+// handleTOTPEnroll starts setting up an authenticator app. It needs the
+// password, so a stolen access token can't put the account behind a
+// second factor only the thief has. Until it is confirmed, login is
+// unaffected and enrolling again starts over with a new secret.
+func (cfg *apiConfig) handleTOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	type response struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "Couldn't find user", err)
+		return
+	}
+
+	err = auth.CheckPasswordHash(params.Password, dbUser.HashedPassword)
+	if err != nil {
+		respondWithError(w, 403, "Password is incorrect", err)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't start enrollment", err)
+		return
+	}
+
+	_, err = cfg.db.StartTOTPEnrollment(r.Context(), database.StartTOTPEnrollmentParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 409, "Two-factor authentication is already enabled", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't start enrollment", err)
+		return
+	}
+
+	respondWithJSON(w, 200, response{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(secret, totpIssuer, dbUser.Email),
+	})
+}
+
+// handleTOTPConfirm turns two-factor authentication on once the user
+// shows their app produces the right codes, and hands out their recovery
+// codes.
+func (cfg *apiConfig) handleTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "No two-factor enrollment in progress", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't confirm enrollment", err)
+		return
+	}
+	if totp.EnabledAt.Valid {
+		respondWithError(w, 409, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(totp.Secret, params.Code, time.Now().UTC())
+	if !ok {
+		respondWithError(w, 400, "Invalid code", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't confirm enrollment", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	enabled, err := qtx.EnableTOTP(r.Context(), database.EnableTOTPParams{
+		UserID:   userID,
+		LastStep: step,
+		Secret:   totp.Secret,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't confirm enrollment", err)
+		return
+	}
+	if enabled == 0 {
+		// Confirmed by a concurrent request, or restarted with a new secret.
+		respondWithError(w, 409, "Enrollment has changed; try again", nil)
+		return
+	}
+
+	codes, err := cfg.replaceRecoveryCodes(r.Context(), qtx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't create recovery codes", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't confirm enrollment", err)
+		return
+	}
+
+	respondWithJSON(w, 200, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// handleTOTPDisable turns two-factor authentication off. It takes a
+// current code or a recovery code, not just an access token.
+func (cfg *apiConfig) handleTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Two-factor authentication isn't enabled", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't disable two-factor authentication", err)
+		return
+	}
+	if !totp.EnabledAt.Valid {
+		respondWithError(w, 404, "Two-factor authentication isn't enabled", nil)
+		return
+	}
+
+	ok, err := cfg.verifySecondFactor(r.Context(), totp, params.Code)
+	if err != nil {
+		if errors.Is(err, errMFALocked) {
+			respondWithError(w, http.StatusTooManyRequests, "Too many attempts; try again later", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't disable two-factor authentication", err)
+		return
+	}
+	if !ok {
+		respondWithError(w, 403, "Invalid code", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't disable two-factor authentication", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	err = qtx.DeleteUserTOTP(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't disable two-factor authentication", err)
+		return
+	}
+	err = qtx.DeleteUserRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't disable two-factor authentication", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't disable two-factor authentication", err)
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
+
+// handleRecoveryCodesRegenerate replaces the user's recovery codes, for
+// when they have used most of them or think they have leaked.
+func (cfg *apiConfig) handleRecoveryCodesRegenerate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "Two-factor authentication isn't enabled", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't create recovery codes", err)
+		return
+	}
+	if !totp.EnabledAt.Valid {
+		respondWithError(w, 404, "Two-factor authentication isn't enabled", nil)
+		return
+	}
+
+	ok, err := cfg.verifySecondFactor(r.Context(), totp, params.Code)
+	if err != nil {
+		if errors.Is(err, errMFALocked) {
+			respondWithError(w, http.StatusTooManyRequests, "Too many attempts; try again later", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't create recovery codes", err)
+		return
+	}
+	if !ok {
+		respondWithError(w, 403, "Invalid code", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't create recovery codes", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	codes, err := cfg.replaceRecoveryCodes(r.Context(), qtx, userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't create recovery codes", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't create recovery codes", err)
+		return
+	}
+
+	respondWithJSON(w, 200, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// handleLoginMFA finishes a login that handleUserLogin answered with an
+// mfa_required challenge.
+func (cfg *apiConfig) handleLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	userID, err := auth.ValidateMFAToken(params.MFAToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid or expired mfa token", err)
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, 500, "Error getting user", err)
+		return
+	}
+	if err == sql.ErrNoRows || !totp.EnabledAt.Valid {
+		// Two-factor was turned off after the challenge was issued; log
+		// in with the password again.
+		respondWithError(w, 401, "Invalid or expired mfa token", err)
+		return
+	}
+
+	ok, err := cfg.verifySecondFactor(r.Context(), totp, params.Code)
+	if err != nil {
+		if errors.Is(err, errMFALocked) {
+			respondWithError(w, http.StatusTooManyRequests, "Too many attempts; try again later", err)
+			return
+		}
+		respondWithError(w, 500, "Error checking code", err)
+		return
+	}
+	if !ok {
+		respondWithError(w, 401, "Invalid code", nil)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Error getting user", err)
+		return
+	}
+
+	user, err := cfg.startSession(r.Context(), dbUser)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't start session", err)
+		return
+	}
+	respondWithJSON(w, 200, user)
+}
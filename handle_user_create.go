@@ -1,17 +1,23 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
+	"net/mail"
+	"strings"
 
 	"github.com/mjossany/Chirpy/internal/auth"
 	"github.com/mjossany/Chirpy/internal/database"
+	"github.com/mjossany/Chirpy/internal/profile"
 )
 
 func (cfg *apiConfig) handleUserCreation(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		Handle   string `json:"handle"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -22,6 +28,22 @@ func (cfg *apiConfig) handleUserCreation(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	address, err := mail.ParseAddress(strings.TrimSpace(params.Email))
+	if err != nil || address.Name != "" {
+		respondWithError(w, 400, "Invalid email address", err)
+		return
+	}
+
+	handle := sql.NullString{}
+	if params.Handle != "" {
+		err = profile.ValidateHandle(params.Handle)
+		if err != nil {
+			respondWithError(w, 400, err.Error(), err)
+			return
+		}
+		handle = sql.NullString{String: params.Handle, Valid: true}
+	}
+
 	hashed_password, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, 500, "Couldn't hash password", err)
@@ -29,21 +51,29 @@ func (cfg *apiConfig) handleUserCreation(w http.ResponseWriter, r *http.Request)
 	}
 
 	dbUser, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{
-		Email:          params.Email,
+		Email:          address.Address,
 		HashedPassword: hashed_password,
+		Handle:         handle,
 	})
 	if err != nil {
+		if isUniqueViolation(err, "users_email_key") {
+			respondWithError(w, 409, "Email is already in use", err)
+			return
+		}
+		if isUniqueViolation(err, "users_lower_handle_idx") {
+			respondWithError(w, 409, "Handle is already taken", err)
+			return
+		}
 		respondWithError(w, 500, "Couldn't create user", err)
 		return
 	}
 
-	user := User{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+	// The account exists either way; if the email doesn't arrive it can be
+	// sent again through /api/users/verify/resend.
+	err = cfg.sendVerificationEmail(r.Context(), dbUser)
+	if err != nil {
+		log.Printf("Couldn't send verification email to user %s: %s", dbUser.ID, err)
 	}
 
-	respondWithJSON(w, 201, user)
+	respondWithJSON(w, 201, cfg.userFromDatabase(dbUser))
 }
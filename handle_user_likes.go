@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleUserLikes(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID", err)
+		return
+	}
+
+	page, err := parseFeedPageRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	_, err = cfg.db.GetUserByID(r.Context(), userUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 404, "User can't be found", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't find user", err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.keyset()
+	rows, err := cfg.db.ListUserLikes(r.Context(), database.ListUserLikesParams{
+		UserID:          userUUID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageSize:        page.fetchSize(),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get likes", err)
+		return
+	}
+
+	rows, next, _ := paginate(page, rows, func(row database.ListUserLikesRow) (time.Time, uuid.UUID) {
+		return row.LikedAt, row.Chirp.ID
+	})
+
+	responseChirps := make([]Chirp, len(rows))
+	for i, row := range rows {
+		responseChirps[i] = chirpFromDatabase(row.Chirp)
+	}
+
+	err = cfg.hydrateChirps(r.Context(), cfg.viewerID(r), chirpRefs(responseChirps)...)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 200, chirpPage{
+		Chirps:     responseChirps,
+		NextCursor: next,
+	})
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/mjossany/Chirpy/internal/auth"
 	"github.com/mjossany/Chirpy/internal/database"
 )
@@ -40,38 +42,71 @@ func (cfg *apiConfig) handleUserLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	totp, err := cfg.db.GetUserTOTP(r.Context(), dbUser.ID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, 500, "Error getting user", err)
+		return
+	}
+	if err == nil && totp.EnabledAt.Valid {
+		mfaToken, err := auth.MakeMFAToken(dbUser.ID, cfg.jwtSecret, mfaTokenExpiresIn)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't generate mfa token", err)
+			return
+		}
+		respondWithJSON(w, 200, mfaChallenge{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		})
+		return
+	}
+
+	user, err := cfg.startSession(r.Context(), dbUser)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't start session", err)
+		return
+	}
+	respondWithJSON(w, 200, user)
+}
+
+// startSession issues a fresh access and refresh token pair for a user
+// who has just proven who they are.
+func (cfg *apiConfig) startSession(ctx context.Context, dbUser database.User) (User, error) {
 	accessTokenExpiresIn := time.Hour
 	jwt, err := auth.MakeJWT(dbUser.ID, cfg.jwtSecret, accessTokenExpiresIn)
 	if err != nil {
-		respondWithError(w, 500, "Couldn't generate jwt authorization", err)
-		return
+		return User{}, err
+	}
+
+	refreshToken, err := issueRefreshToken(ctx, cfg.db, dbUser.ID, uuid.New())
+	if err != nil {
+		return User{}, err
 	}
 
+	user := cfg.userFromDatabase(dbUser)
+	user.Token = jwt
+	user.RefreshToken = refreshToken
+	return user, nil
+}
+
+// issueRefreshToken creates a refresh token in the given family. Logging
+// in starts a new family; refreshing continues the old one. Only the
+// token's hash is stored.
+func issueRefreshToken(ctx context.Context, q *database.Queries, userID, familyID uuid.UUID) (string, error) {
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
-		respondWithError(w, 500, "Couldn't create refresh token", err)
-		return
+		return "", err
 	}
 
 	refreshTokenExpiresIn := time.Hour * 24 * 60
 
-	dbRefreshToken, err := cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    dbUser.ID,
+	_, err = q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		TokenHash: auth.HashToken(refreshToken),
+		UserID:    userID,
 		ExpiresAt: time.Now().UTC().Add(refreshTokenExpiresIn),
+		FamilyID:  familyID,
 	})
 	if err != nil {
-		respondWithError(w, 500, "Couldn't insert refresh token in database", err)
-		return
+		return "", err
 	}
-
-	respondWithJSON(w, 200, User{
-		ID:           dbUser.ID,
-		CreatedAt:    dbUser.CreatedAt,
-		UpdatedAt:    dbUser.UpdatedAt,
-		Email:        dbUser.Email,
-		Token:        jwt,
-		RefreshToken: dbRefreshToken.Token,
-		IsChirpyRed:  dbUser.IsChirpyRed,
-	})
+	return refreshToken, nil
 }
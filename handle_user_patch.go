@@ -0,0 +1,298 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+	chirpymail "github.com/mjossany/Chirpy/internal/mail"
+	"github.com/mjossany/Chirpy/internal/profile"
+)
+
+const emailChangeExpiresIn = 24 * time.Hour
+
+// handleUserPatch updates only the fields present in the request. An
+// empty handle, display name, bio or website clears it. A new password or
+// email needs the current password; a new password signs out every
+// session, and a new email only takes effect once the token sent to it is
+// confirmed through /api/users/email/confirm.
+func (cfg *apiConfig) handleUserPatch(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Handle          *string `json:"handle"`
+		DisplayName     *string `json:"display_name"`
+		Bio             *string `json:"bio"`
+		Website         *string `json:"website"`
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "Couldn't find user", err)
+		return
+	}
+
+	update := database.UpdateUserProfileParams{
+		Handle:      dbUser.Handle,
+		DisplayName: dbUser.DisplayName,
+		Bio:         dbUser.Bio,
+		Website:     dbUser.Website,
+		ID:          userID,
+	}
+	if params.Handle != nil {
+		update.Handle = sql.NullString{}
+		// Keeping the current handle is always allowed, even one that has
+		// since become reserved.
+		if *params.Handle != "" && *params.Handle != dbUser.Handle.String {
+			err = profile.ValidateHandle(*params.Handle)
+			if err != nil {
+				respondWithError(w, 400, err.Error(), err)
+				return
+			}
+		}
+		if *params.Handle != "" {
+			update.Handle = sql.NullString{String: *params.Handle, Valid: true}
+		}
+	}
+	if params.DisplayName != nil {
+		update.DisplayName, err = profile.CleanDisplayName(*params.DisplayName)
+		if err != nil {
+			respondWithError(w, 400, err.Error(), err)
+			return
+		}
+	}
+	if params.Bio != nil {
+		update.Bio, err = profile.CleanBio(*params.Bio)
+		if err != nil {
+			respondWithError(w, 400, err.Error(), err)
+			return
+		}
+	}
+	if params.Website != nil {
+		update.Website, err = profile.CleanWebsite(*params.Website)
+		if err != nil {
+			respondWithError(w, 400, err.Error(), err)
+			return
+		}
+	}
+
+	if params.Password != nil && *params.Password == "" {
+		respondWithError(w, 400, "Password can't be blank", nil)
+		return
+	}
+
+	newEmail := ""
+	if params.Email != nil && !strings.EqualFold(strings.TrimSpace(*params.Email), dbUser.Email) {
+		address, err := mail.ParseAddress(strings.TrimSpace(*params.Email))
+		if err != nil || address.Name != "" {
+			respondWithError(w, 400, "Invalid email address", err)
+			return
+		}
+		newEmail = address.Address
+
+		_, err = cfg.db.GetUserByEmail(r.Context(), newEmail)
+		if err == nil {
+			respondWithError(w, 409, "Email is already in use", nil)
+			return
+		}
+		if err != sql.ErrNoRows {
+			respondWithError(w, 500, "Couldn't update user", err)
+			return
+		}
+	}
+
+	// Either change is enough to take the account over through a password
+	// reset, so neither can be made with an access token alone.
+	if params.Password != nil || newEmail != "" {
+		err = auth.CheckPasswordHash(params.CurrentPassword, dbUser.HashedPassword)
+		if err != nil {
+			respondWithError(w, 403, "Current password is incorrect", err)
+			return
+		}
+	}
+
+	hashedPassword := ""
+	if params.Password != nil {
+		hashedPassword, err = auth.HashPassword(*params.Password)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't hash password", err)
+			return
+		}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't update user", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	dbUser, err = qtx.UpdateUserProfile(r.Context(), update)
+	if err != nil {
+		if isUniqueViolation(err, "users_lower_handle_idx") {
+			respondWithError(w, 409, "Handle is already taken", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't update user", err)
+		return
+	}
+
+	if hashedPassword != "" {
+		dbUser, err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			HashedPassword: hashedPassword,
+			ID:             userID,
+		})
+		if err != nil {
+			respondWithError(w, 500, "Couldn't update password", err)
+			return
+		}
+		// Sign out every other session, as a password reset does.
+		err = qtx.RevokeUserRefreshTokens(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't update password", err)
+			return
+		}
+	}
+
+	var confirmation chirpymail.Message
+	if newEmail != "" {
+		// A new request replaces any earlier one still waiting.
+		err = qtx.DeleteUserEmailChanges(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't start email change", err)
+			return
+		}
+		token, err := auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, 500, "Couldn't start email change", err)
+			return
+		}
+		_, err = qtx.CreateEmailChange(r.Context(), database.CreateEmailChangeParams{
+			TokenHash: auth.HashToken(token),
+			UserID:    userID,
+			NewEmail:  newEmail,
+			ExpiresAt: time.Now().UTC().Add(emailChangeExpiresIn),
+		})
+		if err != nil {
+			respondWithError(w, 500, "Couldn't start email change", err)
+			return
+		}
+		confirmation = chirpymail.Message{
+			To:      newEmail,
+			Subject: "Confirm your new Chirpy email address",
+			Body: fmt.Sprintf("Someone asked to move a Chirpy account to this address. "+
+				"If it was you, confirm it within 24 hours by sending this token to "+
+				"POST /api/users/email/confirm:\n\n%s\n\n"+
+				"If it wasn't, ignore this email and nothing will change.", token),
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't update user", err)
+		return
+	}
+
+	user := cfg.userFromDatabase(dbUser)
+	if newEmail != "" {
+		// The rest of the update is already saved, so a failed send doesn't
+		// fail the request; asking for the change again mails a new token.
+		err = cfg.mailer.Send(r.Context(), confirmation)
+		if err != nil {
+			log.Printf("Couldn't send email change confirmation to user %s: %s", userID, err)
+		}
+		user.PendingEmail = newEmail
+	}
+
+	respondWithJSON(w, 200, user)
+}
+
+// handleEmailChangeConfirm finishes an email change. The token is all the
+// proof needed: only someone reading the new inbox has it.
+func (cfg *apiConfig) handleEmailChangeConfirm(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't confirm email", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	change, err := qtx.ConsumeEmailChange(r.Context(), auth.HashToken(params.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 400, "Invalid or expired token", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't confirm email", err)
+		return
+	}
+	if time.Now().UTC().After(change.ExpiresAt) {
+		// Still commit, so the expired token is cleared out.
+		tx.Commit()
+		respondWithError(w, 400, "Invalid or expired token", nil)
+		return
+	}
+
+	dbUser, err := qtx.UpdateUserEmail(r.Context(), database.UpdateUserEmailParams{
+		Email: change.NewEmail,
+		ID:    change.UserID,
+	})
+	if err != nil {
+		if isUniqueViolation(err, "users_email_key") {
+			respondWithError(w, 409, "Email is already in use", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't confirm email", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't confirm email", err)
+		return
+	}
+
+	respondWithJSON(w, 200, cfg.userFromDatabase(dbUser))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+	chirpymail "github.com/mjossany/Chirpy/internal/mail"
+)
+
+const emailVerificationExpiresIn = 48 * time.Hour
+
+// sendVerificationEmail mails dbUser a token that verifies their current
+// address.
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, dbUser database.User) error {
+	token, err := auth.MakeEmailVerificationToken(dbUser.ID, dbUser.Email, cfg.jwtSecret, emailVerificationExpiresIn)
+	if err != nil {
+		return err
+	}
+	return cfg.mailer.Send(ctx, chirpymail.Message{
+		To:      dbUser.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Welcome to Chirpy! Before you can chirp, verify this address "+
+			"within 48 hours by sending this token to POST /api/users/verify:\n\n%s\n\n"+
+			"If you didn't sign up, ignore this email.", token),
+	})
+}
+
+// handleUserVerify marks an address as verified. The token only works
+// while the account still has that address and it isn't verified yet,
+// so it can be used once.
+func (cfg *apiConfig) handleUserVerify(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	userID, email, err := auth.ValidateEmailVerificationToken(params.Token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 400, "Invalid or expired token", err)
+		return
+	}
+
+	dbUser, err := cfg.db.MarkUserEmailVerified(r.Context(), database.MarkUserEmailVerifiedParams{
+		ID:    userID,
+		Email: email,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 400, "Invalid or expired token", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't verify email", err)
+		return
+	}
+
+	respondWithJSON(w, 200, cfg.userFromDatabase(dbUser))
+}
+
+func (cfg *apiConfig) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "Couldn't find user", err)
+		return
+	}
+
+	if dbUser.EmailVerifiedAt.Valid {
+		respondWithError(w, 409, "Email is already verified", nil)
+		return
+	}
+
+	err = cfg.sendVerificationEmail(r.Context(), dbUser)
+	if err != nil {
+		respondWithError(w, 502, "Couldn't send verification email", err)
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/database"
+	"github.com/mjossany/Chirpy/internal/webauthn"
+)
+
+const maxPasskeyNameLength = 50
+
+var errInvalidChallenge = errors.New("unknown or expired webauthn challenge")
+
+// Passkey is a registered WebAuthn credential as its owner sees it. ID is
+// the credential ID, base64url encoded.
+type Passkey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	BackedUp   bool       `json:"backed_up"`
+}
+
+func passkeyFromDatabase(cred database.WebauthnCredential) Passkey {
+	passkey := Passkey{
+		ID:        base64.RawURLEncoding.EncodeToString(cred.ID),
+		Name:      cred.Name,
+		CreatedAt: cred.CreatedAt,
+		BackedUp:  cred.BackupState,
+	}
+	if cred.LastUsedAt.Valid {
+		passkey.LastUsedAt = &cred.LastUsedAt.Time
+	}
+	return passkey
+}
+
+// newWebAuthnChallenge issues a challenge for userID to register a
+// passkey with, clearing out any that were abandoned.
+func (cfg *apiConfig) newWebAuthnChallenge(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	now := time.Now().UTC()
+	err := cfg.db.DeleteExpiredWebAuthnChallenges(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.db.CreateWebAuthnChallenge(ctx, database.CreateWebAuthnChallengeParams{
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		UserID:    userID,
+		ExpiresAt: now.Add(webauthn.Timeout),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// consumeWebAuthnChallenge finds the challenge a registration's client
+// data answers and uses it up, so each can only be answered once.
+func (cfg *apiConfig) consumeWebAuthnChallenge(ctx context.Context, clientDataJSON []byte) (database.WebauthnChallenge, []byte, error) {
+	clientData, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		return database.WebauthnChallenge{}, nil, errInvalidChallenge
+	}
+	row, err := cfg.db.ConsumeWebAuthnChallenge(ctx, clientData.Challenge)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return database.WebauthnChallenge{}, nil, errInvalidChallenge
+		}
+		return database.WebauthnChallenge{}, nil, err
+	}
+	if time.Now().UTC().After(row.ExpiresAt) {
+		return database.WebauthnChallenge{}, nil, errInvalidChallenge
+	}
+	challenge, err := base64.RawURLEncoding.DecodeString(row.Challenge)
+	if err != nil {
+		return database.WebauthnChallenge{}, nil, err
+	}
+	return row, challenge, nil
+}
+
+// handleWebAuthnRegisterBegin starts adding a passkey to the signed-in
+// user's account. A passkey logs in without the password or a second
+// factor, so both are asked for here, or a stolen access token could
+// leave the thief a way back in.
+func (cfg *apiConfig) handleWebAuthnRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "Couldn't find user", err)
+		return
+	}
+
+	err = auth.CheckPasswordHash(params.Password, dbUser.HashedPassword)
+	if err != nil {
+		respondWithError(w, 403, "Password is incorrect", err)
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, 500, "Couldn't start passkey registration", err)
+		return
+	}
+	if err == nil && totp.EnabledAt.Valid {
+		ok, err := cfg.verifySecondFactor(r.Context(), totp, params.Code)
+		if err != nil {
+			if errors.Is(err, errMFALocked) {
+				respondWithError(w, http.StatusTooManyRequests, "Too many attempts; try again later", err)
+				return
+			}
+			respondWithError(w, 500, "Couldn't start passkey registration", err)
+			return
+		}
+		if !ok {
+			respondWithError(w, 403, "Invalid code", nil)
+			return
+		}
+	}
+
+	existing, err := cfg.db.ListUserWebAuthnCredentials(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't start passkey registration", err)
+		return
+	}
+	exclude := make([][]byte, len(existing))
+	for i, cred := range existing {
+		exclude[i] = cred.ID
+	}
+
+	challenge, err := cfg.newWebAuthnChallenge(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't start passkey registration", err)
+		return
+	}
+
+	displayName := dbUser.DisplayName
+	if displayName == "" {
+		displayName = dbUser.Email
+	}
+	respondWithJSON(w, 200, cfg.relyingParty.CreationOptions(webauthn.User{
+		ID:          userID[:],
+		Name:        dbUser.Email,
+		DisplayName: displayName,
+	}, challenge, exclude))
+}
+
+func (cfg *apiConfig) handleWebAuthnRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name       string                    `json:"name"`
+		Credential webauthn.CreationResponse `json:"credential"`
+	}
+
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if len([]rune(name)) > maxPasskeyNameLength {
+		respondWithError(w, 400, "Passkey name is too long", nil)
+		return
+	}
+
+	row, challenge, err := cfg.consumeWebAuthnChallenge(r.Context(), params.Credential.Response.ClientDataJSON)
+	if err != nil {
+		if errors.Is(err, errInvalidChallenge) {
+			respondWithError(w, 400, "Unknown or expired challenge", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't register passkey", err)
+		return
+	}
+	if row.UserID != userID {
+		respondWithError(w, 400, "Unknown or expired challenge", nil)
+		return
+	}
+
+	cred, err := cfg.relyingParty.VerifyRegistration(challenge, params.Credential)
+	if err != nil {
+		respondWithError(w, 400, "Couldn't verify passkey", err)
+		return
+	}
+
+	dbCred, err := cfg.db.CreateWebAuthnCredential(r.Context(), database.CreateWebAuthnCredentialParams{
+		ID:             cred.ID,
+		UserID:         userID,
+		Name:           name,
+		PublicKey:      cred.PublicKey,
+		SignCount:      int64(cred.SignCount),
+		Aaguid:         cred.AAGUID,
+		BackupEligible: cred.BackupEligible,
+		BackupState:    cred.BackupState,
+	})
+	if err != nil {
+		if isUniqueViolation(err, "webauthn_credentials_pkey") {
+			respondWithError(w, 409, "Passkey is already registered", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't register passkey", err)
+		return
+	}
+
+	respondWithJSON(w, 201, passkeyFromDatabase(dbCred))
+}
+
+// handleWebAuthnLoginBegin starts a passkey login. No account is named:
+// the authenticator offers the passkeys it holds for the site, and says
+// whose it used in the response. Anyone can call it, so the challenge is
+// signed rather than stored, and calling it costs the database nothing.
+func (cfg *apiConfig) handleWebAuthnLoginBegin(w http.ResponseWriter, r *http.Request) {
+	challenge, err := webauthn.NewSignedChallenge([]byte(cfg.jwtSecret), time.Now().UTC().Add(webauthn.Timeout))
+	if err != nil {
+		respondWithError(w, 500, "Couldn't start passkey login", err)
+		return
+	}
+
+	respondWithJSON(w, 200, cfg.relyingParty.RequestOptions(challenge, nil))
+}
+
+// handleWebAuthnLoginFinish checks a passkey assertion and, if it holds,
+// signs the user in just as a password login does.
+func (cfg *apiConfig) handleWebAuthnLoginFinish(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	params := webauthn.AssertionResponse{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't decode parameters", err)
+		return
+	}
+
+	clientData, err := webauthn.ParseClientData(params.Response.ClientDataJSON)
+	if err != nil {
+		respondWithError(w, 400, "Unknown or expired challenge", err)
+		return
+	}
+	challenge, err := base64.RawURLEncoding.DecodeString(clientData.Challenge)
+	if err != nil {
+		respondWithError(w, 400, "Unknown or expired challenge", err)
+		return
+	}
+	now := time.Now().UTC()
+	err = webauthn.VerifySignedChallenge([]byte(cfg.jwtSecret), challenge, now)
+	if err != nil {
+		respondWithError(w, 400, "Unknown or expired challenge", err)
+		return
+	}
+
+	dbCred, err := cfg.db.GetWebAuthnCredential(r.Context(), params.RawID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, 401, "Unknown passkey", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't log in with passkey", err)
+		return
+	}
+	if !bytes.Equal(params.Response.UserHandle, dbCred.UserID[:]) {
+		respondWithError(w, 401, "Unknown passkey", nil)
+		return
+	}
+
+	cred, err := cfg.relyingParty.VerifyAssertion(challenge, webauthn.Credential{
+		ID:             dbCred.ID,
+		PublicKey:      dbCred.PublicKey,
+		SignCount:      uint32(dbCred.SignCount),
+		AAGUID:         dbCred.Aaguid,
+		BackupEligible: dbCred.BackupEligible,
+		BackupState:    dbCred.BackupState,
+	}, params)
+	if err != nil {
+		respondWithError(w, 401, "Couldn't verify passkey", err)
+		return
+	}
+
+	// A challenge is only recorded once it has been answered, so failed
+	// attempts leave nothing behind. The record is kept for a full
+	// Timeout, which outlasts the challenge itself.
+	err = cfg.db.DeleteExpiredUsedWebAuthnChallenges(r.Context(), now)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't log in with passkey", err)
+		return
+	}
+	used, err := cfg.db.UseWebAuthnChallenge(r.Context(), database.UseWebAuthnChallengeParams{
+		Challenge: clientData.Challenge,
+		ExpiresAt: now.Add(webauthn.Timeout),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't log in with passkey", err)
+		return
+	}
+	if used == 0 {
+		respondWithError(w, 400, "Unknown or expired challenge", nil)
+		return
+	}
+
+	// Only move the counter on from the value just checked, so two logins
+	// racing with the same counter can't both succeed.
+	updated, err := cfg.db.UpdateWebAuthnCredentialUse(r.Context(), database.UpdateWebAuthnCredentialUseParams{
+		SignCount:         int64(cred.SignCount),
+		BackupState:       cred.BackupState,
+		ID:                dbCred.ID,
+		PreviousSignCount: dbCred.SignCount,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't log in with passkey", err)
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, 401, "Couldn't verify passkey", nil)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(r.Context(), dbCred.UserID)
+	if err != nil {
+		respondWithError(w, 500, "Error getting user", err)
+		return
+	}
+
+	user, err := cfg.startSession(r.Context(), dbUser)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't start session", err)
+		return
+	}
+	respondWithJSON(w, 200, user)
+}
+
+func (cfg *apiConfig) handlePasskeyList(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	dbCreds, err := cfg.db.ListUserWebAuthnCredentials(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't get passkeys", err)
+		return
+	}
+
+	passkeys := make([]Passkey, len(dbCreds))
+	for i, cred := range dbCreds {
+		passkeys[i] = passkeyFromDatabase(cred)
+	}
+
+	respondWithJSON(w, 200, passkeys)
+}
+
+func (cfg *apiConfig) handlePasskeyDelete(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	credentialID, err := base64.RawURLEncoding.DecodeString(r.PathValue("credentialID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid passkey id", err)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteWebAuthnCredential(r.Context(), database.DeleteWebAuthnCredentialParams{
+		ID:     credentialID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't delete passkey", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Couldn't find passkey", nil)
+		return
+	}
+
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/auth"
+	"github.com/mjossany/Chirpy/internal/entities"
+	"github.com/mjossany/Chirpy/internal/pubsub"
+	"github.com/mjossany/Chirpy/internal/websocket"
+)
+
+const (
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = 30 * time.Second
+	wsWriteWait      = 10 * time.Second
+	wsMaxMessageSize = 4096
+	wsMaxChannels    = 20
+)
+
+const (
+	wsChannelTimeline      = "timeline"
+	wsChannelNotifications = "notifications"
+	wsChannelUserPrefix    = "user:"
+	wsChannelHashtagPrefix = "hashtag:"
+)
+
+// wsClient is one open socket and the channels it's subscribed to.
+type wsClient struct {
+	cfg    *apiConfig
+	userID uuid.UUID
+	conn   *websocket.Conn
+
+	mu        sync.RWMutex
+	channels  map[string]bool
+	following map[uuid.UUID]bool
+}
+
+type wsMessage struct {
+	Type     string          `json:"type"`
+	Channel  string          `json:"channel,omitempty"`
+	Channels []string        `json:"channels,omitempty"`
+	Event    string          `json:"event,omitempty"`
+	ID       uint64          `json:"id,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+func (cfg *apiConfig) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	authorization, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(authorization, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, 401, "Invalid authorization", err)
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) {
+			respondWithError(w, http.StatusBadRequest, "Invalid WebSocket handshake", err)
+			return
+		}
+		respondWithError(w, 500, "Couldn't open WebSocket", err)
+		return
+	}
+	defer conn.Close()
+
+	client := &wsClient{
+		cfg:      cfg,
+		userID:   userID,
+		conn:     conn,
+		channels: make(map[string]bool),
+	}
+
+	sub, _, _ := cfg.events.Subscribe(0, client.wants)
+	defer sub.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go client.writeLoop(r.Context(), sub, done)
+
+	client.readLoop(r.Context())
+}
+
+// readLoop handles subscribe and unsubscribe requests until the socket
+// closes or goes quiet for longer than wsPongWait.
+func (c *wsClient) readLoop(ctx context.Context) {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func() {
+		c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		type request struct {
+			Action  string `json:"action"`
+			Channel string `json:"channel"`
+		}
+		req := request{}
+		err = json.Unmarshal(data, &req)
+		if err != nil {
+			c.send(wsMessage{Type: "error", Error: "Couldn't decode message"})
+			continue
+		}
+
+		channel, err := normalizeWSChannel(req.Channel)
+		if err != nil {
+			c.send(wsMessage{Type: "error", Channel: req.Channel, Error: err.Error()})
+			continue
+		}
+
+		switch req.Action {
+		case "subscribe":
+			err = c.subscribe(ctx, channel)
+			if err != nil {
+				c.send(wsMessage{Type: "error", Channel: channel, Error: err.Error()})
+				continue
+			}
+			c.send(wsMessage{Type: "subscribed", Channel: channel})
+		case "unsubscribe":
+			c.mu.Lock()
+			delete(c.channels, channel)
+			c.mu.Unlock()
+			c.send(wsMessage{Type: "unsubscribed", Channel: channel})
+		default:
+			c.send(wsMessage{Type: "error", Error: "Unknown action"})
+		}
+	}
+}
+
+// writeLoop forwards matching events and pings the client. Events queue in
+// the subscription's buffer rather than here, so a client that can't keep
+// up is dropped by the broker instead of holding up everyone else.
+func (c *wsClient) writeLoop(ctx context.Context, sub *pubsub.Subscription, done <-chan struct{}) {
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				c.conn.WriteClose(websocket.CloseTryAgainLater, "Too slow to keep up")
+				c.conn.Close()
+				return
+			}
+			if event.Type == eventFollowsChanged {
+				err := c.loadFollowing(ctx)
+				if err != nil {
+					log.Printf("Couldn't reload following for socket: %s", err)
+				}
+				continue
+			}
+			channels := c.matchingChannels(event)
+			if len(channels) == 0 {
+				continue
+			}
+			err := c.send(wsMessage{
+				Type:     "event",
+				Channels: channels,
+				Event:    event.Type,
+				ID:       event.ID,
+				Data:     event.Data,
+			})
+			if err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-ping.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err := c.conn.WriteMessage(websocket.PingMessage, nil)
+			if err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (c *wsClient) send(msg wsMessage) error {
+	dat, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteMessage(websocket.TextMessage, dat)
+}
+
+func (c *wsClient) subscribe(ctx context.Context, channel string) error {
+	c.mu.RLock()
+	count := len(c.channels)
+	c.mu.RUnlock()
+	if count >= wsMaxChannels {
+		return errors.New("Too many channels")
+	}
+
+	if channel == wsChannelTimeline {
+		err := c.loadFollowing(ctx)
+		if err != nil {
+			log.Printf("Couldn't load following for socket: %s", err)
+			return errors.New("Couldn't load timeline")
+		}
+	}
+
+	c.mu.Lock()
+	c.channels[channel] = true
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *wsClient) loadFollowing(ctx context.Context) error {
+	followeeIDs, err := c.cfg.db.ListFolloweeIDs(ctx, c.userID)
+	if err != nil {
+		return err
+	}
+	following := make(map[uuid.UUID]bool, len(followeeIDs))
+	for _, id := range followeeIDs {
+		following[id] = true
+	}
+
+	c.mu.Lock()
+	c.following = following
+	c.mu.Unlock()
+	return nil
+}
+
+// wants is the broker filter for this socket. It runs on the publisher's
+// goroutine, so it only reads in-memory state.
+func (c *wsClient) wants(event pubsub.Event) bool {
+	if event.HasTopic(followsTopic(c.userID)) {
+		return true
+	}
+	return len(c.matchingChannels(event)) > 0
+}
+
+func (c *wsClient) matchingChannels(event pubsub.Event) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var channels []string
+	for channel := range c.channels {
+		switch {
+		case channel == wsChannelTimeline:
+			for _, topic := range event.Topics {
+				id, ok := strings.CutPrefix(topic, wsChannelUserPrefix)
+				if !ok {
+					continue
+				}
+				authorID, err := uuid.Parse(id)
+				if err == nil && c.following[authorID] {
+					channels = append(channels, channel)
+					break
+				}
+			}
+		case channel == wsChannelNotifications:
+			if event.HasTopic(notificationTopic(c.userID)) {
+				channels = append(channels, channel)
+			}
+		default:
+			// user:<id> and hashtag:<tag> channels share their event topic's name.
+			if event.HasTopic(channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	return channels
+}
+
+// normalizeWSChannel validates a channel name and returns the form it's
+// tracked by.
+func normalizeWSChannel(channel string) (string, error) {
+	switch {
+	case channel == wsChannelTimeline || channel == wsChannelNotifications:
+		return channel, nil
+	case strings.HasPrefix(channel, wsChannelUserPrefix):
+		userID, err := uuid.Parse(strings.TrimPrefix(channel, wsChannelUserPrefix))
+		if err != nil {
+			return "", errors.New("Invalid user ID")
+		}
+		return userTopic(userID), nil
+	case strings.HasPrefix(channel, wsChannelHashtagPrefix):
+		tag := entities.NormalizeHashtag(strings.TrimPrefix(channel, wsChannelHashtagPrefix))
+		if tag == "" {
+			return "", errors.New("Invalid hashtag")
+		}
+		return hashtagTopic(tag), nil
+	}
+	return "", errors.New("Unknown channel")
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/mjossany/Chirpy/internal/auth"
 	"github.com/mjossany/Chirpy/internal/database"
 )
 
+const maxChirpLength = 140
+
 func (cfg *apiConfig) handleChirpCreation(w http.ResponseWriter, r *http.Request) {
 	authorization, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -22,8 +27,22 @@ func (cfg *apiConfig) handleChirpCreation(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	dbUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't find user", err)
+		return
+	}
+	if !dbUser.EmailVerifiedAt.Valid {
+		respondWithError(w, 403, "Verify your email address before chirping", nil)
+		return
+	}
+
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string             `json:"body"`
+		InReplyTo *uuid.UUID         `json:"in_reply_to"`
+		QuoteOf   *uuid.UUID         `json:"quote_of"`
+		Media     []attachmentParams `json:"media"`
+		Poll      *pollParams        `json:"poll"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -34,7 +53,6 @@ func (cfg *apiConfig) handleChirpCreation(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	const maxChirpLength = 140
 	if len(params.Body) > maxChirpLength {
 		respondWithError(w, http.StatusBadRequest, "Chirp is too long", nil)
 		return
@@ -47,22 +65,144 @@ func (cfg *apiConfig) handleChirpCreation(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	dbChirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleanedChirp,
-		UserID: userID,
-	})
+	parentID := uuid.NullUUID{}
+	parentAuthorID := uuid.Nil
+	rootID := uuid.NullUUID{}
+	if params.InReplyTo != nil {
+		parent, err := cfg.db.GetChirp(r.Context(), *params.InReplyTo)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusBadRequest, "Couldn't find chirp to reply to", err)
+				return
+			}
+			respondWithError(w, 500, "Couldn't find chirp to reply to", err)
+			return
+		}
+		if parent.DeletedAt.Valid {
+			respondWithError(w, http.StatusBadRequest, "Can't reply to a deleted chirp", nil)
+			return
+		}
+		if parent.RechirpOf.Valid {
+			respondWithError(w, http.StatusBadRequest, "Can't reply to a rechirp", nil)
+			return
+		}
+		parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		parentAuthorID = parent.UserID
+		rootID = parent.RootID
+		if !rootID.Valid {
+			rootID = parentID
+		}
+	}
+
+	quoteOf := uuid.NullUUID{}
+	if params.QuoteOf != nil {
+		quoted, err := cfg.getShareableChirp(r.Context(), *params.QuoteOf)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusBadRequest, "Couldn't find chirp to quote", err)
+				return
+			}
+			respondWithError(w, 500, "Couldn't find chirp to quote", err)
+			return
+		}
+		quoteOf = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
+
+	msg, err := cfg.validateAttachments(r.Context(), userID, params.Media)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't find media to attach", err)
+		return
+	}
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg, nil)
+		return
+	}
+
+	if params.Poll != nil {
+		msg := validatePoll(params.Poll, dbUser.IsChirpyRed, time.Now().UTC())
+		if msg != "" {
+			respondWithError(w, http.StatusBadRequest, msg, nil)
+			return
+		}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
 	if err != nil {
 		respondWithError(w, 500, "Couldn't create chirp", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
 
-	respondWithJSON(w, 201, Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+	dbChirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+		Body:     cleanedChirp,
+		UserID:   userID,
+		ParentID: parentID,
+		RootID:   rootID,
+		QuoteOf:  quoteOf,
 	})
+	if err != nil {
+		respondWithError(w, 500, "Couldn't create chirp", err)
+		return
+	}
+
+	err = saveAttachments(r.Context(), qtx, dbChirp.ID, params.Media)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't attach media", err)
+		return
+	}
+
+	if params.Poll != nil {
+		err = savePoll(r.Context(), qtx, dbChirp.ID, params.Poll)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't create poll", err)
+			return
+		}
+	}
+
+	notifications, err := saveChirpEntities(r.Context(), qtx, dbChirp)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't save chirp entities", err)
+		return
+	}
+
+	if parentID.Valid {
+		created, err := notify(r.Context(), qtx, parentAuthorID, userID, notificationKindReply, parentID)
+		if err != nil {
+			respondWithError(w, 500, "Couldn't create notification", err)
+			return
+		}
+		notifications = append(notifications, created...)
+	}
+
+	if quoteOf.Valid {
+		err = qtx.AdjustChirpShareCounts(r.Context(), database.AdjustChirpShareCountsParams{
+			QuoteDelta: 1,
+			ID:         quoteOf.UUID,
+		})
+		if err != nil {
+			respondWithError(w, 500, "Couldn't update quote count", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "Couldn't create chirp", err)
+		return
+	}
+
+	cfg.publishChirp(r.Context(), dbChirp)
+	cfg.publishNotifications(notifications...)
+
+	chirp := chirpFromDatabase(dbChirp)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, &chirp)
+	if err != nil {
+		respondWithError(w, 500, "Couldn't load chirps", err)
+		return
+	}
+
+	respondWithJSON(w, 201, chirp)
 }
 
 func cleanChirp(body string) string {
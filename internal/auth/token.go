@@ -0,0 +1,16 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken returns the form a random one-time token is stored in, so a
+// leaked table doesn't hand out working tokens. A plain SHA-256 is only
+// enough because tokens from MakeRefreshToken carry 256 bits of
+// randomness, leaving nothing to brute-force. Shorter secrets, like
+// recovery codes, need HashRecoveryCode.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestHashToken(t *testing.T) {
+	token1, _ := MakeRefreshToken()
+	token2, _ := MakeRefreshToken()
+
+	tests := []struct {
+		name      string
+		a         string
+		b         string
+		wantEqual bool
+	}{
+		{
+			name:      "Same token hashes the same",
+			a:         token1,
+			b:         token1,
+			wantEqual: true,
+		},
+		{
+			name:      "Different tokens hash differently",
+			a:         token1,
+			b:         token2,
+			wantEqual: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashToken(tt.a) == HashToken(tt.b); got != tt.wantEqual {
+				t.Errorf("HashToken() equal = %v, want %v", got, tt.wantEqual)
+			}
+		})
+	}
+
+	if HashToken(token1) == token1 {
+		t.Errorf("HashToken() returned the token itself")
+	}
+	if got := HashToken(""); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("HashToken(\"\") = %q", got)
+	}
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// rfc6238Secret is the SHA-1 key from the RFC 6238 test vectors,
+// "12345678901234567890", base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// The RFC lists 8-digit codes; these are their last 6 digits.
+	tests := []struct {
+		name     string
+		unixTime int64
+		wantCode string
+	}{
+		{name: "59", unixTime: 59, wantCode: "287082"},
+		{name: "1111111109", unixTime: 1111111109, wantCode: "081804"},
+		{name: "1111111111", unixTime: 1111111111, wantCode: "050471"},
+		{name: "1234567890", unixTime: 1234567890, wantCode: "005924"},
+		{name: "2000000000", unixTime: 2000000000, wantCode: "279037"},
+		{name: "20000000000", unixTime: 20000000000, wantCode: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCode, err := TOTPCode(rfc6238Secret, time.Unix(tt.unixTime, 0))
+			if err != nil {
+				t.Fatalf("TOTPCode() error = %v", err)
+			}
+			if gotCode != tt.wantCode {
+				t.Errorf("TOTPCode() = %v, want %v", gotCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	secret, _ := GenerateTOTPSecret()
+	current, _ := TOTPCode(secret, now)
+	previous, _ := TOTPCode(secret, now.Add(-30*time.Second))
+	stale, _ := TOTPCode(secret, now.Add(-90*time.Second))
+	otherSecret, _ := GenerateTOTPSecret()
+	other, _ := TOTPCode(otherSecret, now)
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{
+			name:     "Current code",
+			secret:   secret,
+			code:     current,
+			wantStep: TOTPStep(now),
+			wantOK:   true,
+		},
+		{
+			name:     "Previous code within skew",
+			secret:   secret,
+			code:     previous,
+			wantStep: TOTPStep(now) - 1,
+			wantOK:   true,
+		},
+		{
+			name:     "Code with a space",
+			secret:   secret,
+			code:     current[:3] + " " + current[3:],
+			wantStep: TOTPStep(now),
+			wantOK:   true,
+		},
+		{
+			name:   "Stale code",
+			secret: secret,
+			code:   stale,
+			wantOK: false,
+		},
+		{
+			name:   "Another secret's code",
+			secret: secret,
+			code:   other,
+			wantOK: false,
+		},
+		{
+			name:   "Malformed secret",
+			secret: "not base32!",
+			code:   current,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOK := ValidateTOTP(tt.secret, tt.code, now)
+			if gotOK != tt.wantOK {
+				t.Errorf("ValidateTOTP() ok = %v, want %v", gotOK, tt.wantOK)
+				return
+			}
+			if gotOK && gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP() step = %v, want %v", gotStep, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("JBSWY3DPEHPK3PXP", "Chirpy", "user@example.com")
+	for _, want := range []string{
+		"otpauth://totp/Chirpy:user@example.com?",
+		"secret=JBSWY3DPEHPK3PXP",
+		"issuer=Chirpy",
+		"digits=6",
+		"period=30",
+	} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("TOTPURI() = %q, missing %q", uri, want)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("got %d codes, want 10", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 14 || code[4] != '-' || code[9] != '-' {
+			t.Errorf("code %q isn't of the form xxxx-xxxx-xxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q generated twice", code)
+		}
+		seen[code] = true
+
+		retyped := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if NormalizeRecoveryCode(retyped) != NormalizeRecoveryCode(code) {
+			t.Errorf("NormalizeRecoveryCode(%q) != NormalizeRecoveryCode(%q)", retyped, code)
+		}
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	alice := uuid.New()
+	bob := uuid.New()
+	want := HashRecoveryCode(alice, "abcd-efgh-jkmn", "secret")
+
+	tests := []struct {
+		name      string
+		userID    uuid.UUID
+		code      string
+		secret    string
+		wantEqual bool
+	}{
+		{name: "Retyped code", userID: alice, code: "ABCD EFGH JKMN", secret: "secret", wantEqual: true},
+		{name: "Different code", userID: alice, code: "abcd-efgh-jkmp", secret: "secret"},
+		{name: "Different user", userID: bob, code: "abcd-efgh-jkmn", secret: "secret"},
+		{name: "Different secret", userID: alice, code: "abcd-efgh-jkmn", secret: "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HashRecoveryCode(tt.userID, tt.code, tt.secret) == want
+			if got != tt.wantEqual {
+				t.Errorf("HashRecoveryCode() equal = %v, want %v", got, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestValidateMFAToken(t *testing.T) {
+	userID := uuid.New()
+	mfaToken, _ := MakeMFAToken(userID, "secret", time.Minute)
+	accessToken, _ := MakeJWT(userID, "secret", time.Minute)
+
+	if _, err := ValidateMFAToken(mfaToken, "secret"); err != nil {
+		t.Errorf("ValidateMFAToken() error = %v", err)
+	}
+	if _, err := ValidateMFAToken(accessToken, "secret"); err == nil {
+		t.Error("ValidateMFAToken() accepted an access token")
+	}
+	if _, err := ValidateJWT(mfaToken, "secret"); err == nil {
+		t.Error("ValidateJWT() accepted an MFA challenge token")
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateEmailVerificationToken(t *testing.T) {
+	userID := uuid.New()
+	validToken, _ := MakeEmailVerificationToken(userID, "user@example.com", "secret", time.Hour)
+	expiredToken, _ := MakeEmailVerificationToken(userID, "user@example.com", "secret", -time.Hour)
+	accessToken, _ := MakeJWT(userID, "secret", time.Hour)
+
+	tests := []struct {
+		name        string
+		tokenString string
+		tokenSecret string
+		wantUserID  uuid.UUID
+		wantEmail   string
+		wantErr     bool
+	}{
+		{
+			name:        "Valid token",
+			tokenString: validToken,
+			tokenSecret: "secret",
+			wantUserID:  userID,
+			wantEmail:   "user@example.com",
+			wantErr:     false,
+		},
+		{
+			name:        "Wrong secret",
+			tokenString: validToken,
+			tokenSecret: "wrong_secret",
+			wantErr:     true,
+		},
+		{
+			name:        "Expired token",
+			tokenString: expiredToken,
+			tokenSecret: "secret",
+			wantErr:     true,
+		},
+		{
+			name:        "Access token",
+			tokenString: accessToken,
+			tokenSecret: "secret",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID, gotEmail, err := ValidateEmailVerificationToken(tt.tokenString, tt.tokenSecret)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEmailVerificationToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotUserID != tt.wantUserID || gotEmail != tt.wantEmail {
+				t.Errorf("ValidateEmailVerificationToken() = %v, %q, want %v, %q", gotUserID, gotEmail, tt.wantUserID, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestValidateJWTRejectsVerificationToken(t *testing.T) {
+	token, _ := MakeEmailVerificationToken(uuid.New(), "user@example.com", "secret", time.Hour)
+	if _, err := ValidateJWT(token, "secret"); err == nil {
+		t.Error("ValidateJWT() accepted an email verification token")
+	}
+}
@@ -0,0 +1,38 @@
+// Package blobstore stores uploaded files under string keys, either on the
+// local filesystem or in an S3-compatible bucket.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrNotFound is returned by Get for a key that holds nothing.
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or try to
+// climb out of the store with "..".
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore keeps blobs under slash-separated keys such as
+// "media/1234.jpg". Put overwrites and Delete of a missing key succeeds.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	// URL is where clients can fetch the blob from.
+	URL(key string) string
+}
+
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -7,29 +7,106 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const adjustChirpShareCounts = `-- name: AdjustChirpShareCounts :exec
+UPDATE chirp
+SET
+    rechirp_count = rechirp_count + $1,
+    quote_count = quote_count + $2
+WHERE
+    id = $3
+`
+
+type AdjustChirpShareCountsParams struct {
+	RechirpDelta int32
+	QuoteDelta   int32
+	ID           uuid.UUID
+}
+
+func (q *Queries) AdjustChirpShareCounts(ctx context.Context, arg AdjustChirpShareCountsParams) error {
+	_, err := q.db.ExecContext(ctx, adjustChirpShareCounts, arg.RechirpDelta, arg.QuoteDelta, arg.ID)
+	return err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirp (id, created_at, updated_at, body, user_id)
+INSERT INTO chirp (id, created_at, updated_at, body, user_id, parent_id, root_id, quote_of, is_quote)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $5::uuid IS NOT NULL
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body     string
+	UserID   uuid.UUID
+	ParentID uuid.NullUUID
+	RootID   uuid.NullUUID
+	QuoteOf  uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ParentID,
+		arg.RootID,
+		arg.QuoteOf,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirp (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2
+)
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at
+`
+
+type CreateRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOf)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +114,16 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
 	)
 	return i, err
 }
@@ -56,13 +143,66 @@ func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) error
 	return err
 }
 
-const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirp
-ORDER BY created_at
+const deleteOrphanedTombstone = `-- name: DeleteOrphanedTombstone :one
+DELETE FROM chirp
+WHERE id = $1
+AND deleted_at IS NOT NULL
+AND NOT EXISTS (
+    SELECT 1 FROM chirp AS replies
+    WHERE replies.parent_id = $1
+)
+RETURNING parent_id
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+func (q *Queries) DeleteOrphanedTombstone(ctx context.Context, id uuid.UUID) (uuid.NullUUID, error) {
+	row := q.db.QueryRowContext(ctx, deleteOrphanedTombstone, id)
+	var parent_id uuid.NullUUID
+	err := row.Scan(&parent_id)
+	return parent_id, err
+}
+
+const deleteRechirp = `-- name: DeleteRechirp :one
+DELETE FROM chirp
+WHERE user_id = $1 AND rechirp_of = $2
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at
+`
+
+type DeleteRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+	)
+	return i, err
+}
+
+const deleteRechirpsOf = `-- name: DeleteRechirpsOf :many
+DELETE FROM chirp
+WHERE rechirp_of = $1
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at
+`
+
+func (q *Queries) DeleteRechirpsOf(ctx context.Context, rechirpOf uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, deleteRechirpsOf, rechirpOf)
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +216,16 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -91,7 +241,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirp
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at FROM chirp
 WHERE id = $1
 `
 
@@ -104,18 +254,369 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+	)
+	return i, err
+}
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at FROM chirp
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
 	)
 	return i, err
 }
 
-const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirp
-WHERE user_id = $1
-ORDER BY created_at
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at FROM chirp
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getThreadChirps = `-- name: GetThreadChirps :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at FROM chirp
+WHERE id = $1 OR root_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) GetThreadChirps(ctx context.Context, id uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getThreadChirps, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hasReplies = `-- name: HasReplies :one
+SELECT EXISTS (
+    SELECT 1 FROM chirp
+    WHERE parent_id = $1
+)
+`
+
+func (q *Queries) HasReplies(ctx context.Context, parentID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasReplies, parentID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listChirpsAfter = `-- name: ListChirpsAfter :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at FROM chirp
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+AND deleted_at IS NULL
+AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type ListChirpsAfterParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListChirpsAfter(ctx context.Context, arg ListChirpsAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAfter,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsBefore = `-- name: ListChirpsBefore :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at FROM chirp
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+AND deleted_at IS NULL
+AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListChirpsBeforeParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListChirpsBefore(ctx context.Context, arg ListChirpsBeforeParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsBefore,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimelineAfter = `-- name: ListTimelineAfter :many
+SELECT chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.parent_id, chirp.root_id, chirp.deleted_at, chirp.like_count, chirp.rechirp_of, chirp.quote_of, chirp.is_quote, chirp.rechirp_count, chirp.quote_count, chirp.edited_at FROM chirp
+JOIN follows ON follows.followee_id = chirp.user_id
+WHERE follows.follower_id = $1
+AND chirp.deleted_at IS NULL
+AND (
+    $2::timestamp IS NULL
+    OR (chirp.created_at, chirp.id) > ($2::timestamp, $3::uuid)
+)
+ORDER BY chirp.created_at ASC, chirp.id ASC
+LIMIT $4
+`
+
+type ListTimelineAfterParams struct {
+	FollowerID      uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListTimelineAfter(ctx context.Context, arg ListTimelineAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listTimelineAfter,
+		arg.FollowerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimelineBefore = `-- name: ListTimelineBefore :many
+SELECT chirp.id, chirp.created_at, chirp.updated_at, chirp.body, chirp.user_id, chirp.parent_id, chirp.root_id, chirp.deleted_at, chirp.like_count, chirp.rechirp_of, chirp.quote_of, chirp.is_quote, chirp.rechirp_count, chirp.quote_count, chirp.edited_at FROM chirp
+JOIN follows ON follows.followee_id = chirp.user_id
+WHERE follows.follower_id = $1
+AND chirp.deleted_at IS NULL
+AND (
+    $2::timestamp IS NULL
+    OR (chirp.created_at, chirp.id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY chirp.created_at DESC, chirp.id DESC
+LIMIT $4
 `
 
-func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
+type ListTimelineBeforeParams struct {
+	FollowerID      uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListTimelineBefore(ctx context.Context, arg ListTimelineBeforeParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listTimelineBefore,
+		arg.FollowerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -129,6 +630,16 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.EditedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -142,3 +653,64 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirp
+SET
+    body = '',
+    quote_of = NULL,
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE
+    id = $1 AND user_id = $2
+`
+
+type TombstoneChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) TombstoneChirp(ctx context.Context, arg TombstoneChirpParams) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, arg.ID, arg.UserID)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirp
+SET
+    body = $1,
+    edited_at = NOW(),
+    updated_at = NOW()
+WHERE
+    id = $2 AND user_id = $3
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, rechirp_of, quote_of, is_quote, rechirp_count, quote_count, edited_at
+`
+
+type UpdateChirpBodyParams struct {
+	Body   string
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.Body, arg.ID, arg.UserID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.EditedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,94 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Entity is a token found in a chirp body. Start and End are offsets in
+// Unicode code points, End exclusive, and include the leading sigil.
+type Entity struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// Hashtags returns the #tags in body in order of appearance, Text without
+// the leading '#'. A tag must contain at least one letter, so "#1" is not
+// one.
+func Hashtags(body string) []Entity {
+	return scan(body, '#', func(word []rune) bool {
+		for _, r := range word {
+			if unicode.IsLetter(r) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MaxHandleLength is the longest handle a user can have.
+const MaxHandleLength = 15
+
+// Mentions returns the @handles in body in order of appearance, Text
+// without the leading '@'. Only words that could be a handle count.
+func Mentions(body string) []Entity {
+	return scan(body, '@', func(word []rune) bool {
+		return ValidHandle(string(word))
+	})
+}
+
+// ValidHandle reports whether handle is made of 1 to MaxHandleLength ASCII
+// letters, digits and underscores.
+func ValidHandle(handle string) bool {
+	if len(handle) == 0 || len(handle) > MaxHandleLength {
+		return false
+	}
+	for _, r := range handle {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeHandle returns the form a handle is compared by.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(handle, "@"))
+}
+
+// NormalizeHashtag returns the form a tag is stored and looked up by.
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+// scan finds sigil-prefixed words. The sigil only counts at the start of
+// body or after a character that can't be part of a word, which keeps
+// things like "a#b" and email addresses out.
+func scan(body string, sigil rune, valid func([]rune) bool) []Entity {
+	runes := []rune(body)
+	var found []Entity
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != sigil || (i > 0 && isWordRune(runes[i-1])) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := runes[i+1 : end]
+		if len(word) > 0 && valid(word) {
+			found = append(found, Entity{
+				Text:  string(word),
+				Start: i,
+				End:   end,
+			})
+		}
+		i = end - 1
+	}
+	return found
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
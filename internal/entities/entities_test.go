@@ -0,0 +1,150 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Entity
+	}{
+		{
+			name: "Single tag",
+			body: "I love #golang",
+			want: []Entity{{Text: "golang", Start: 7, End: 14}},
+		},
+		{
+			name: "Tag at start and punctuation after",
+			body: "#chirpy, the best",
+			want: []Entity{{Text: "chirpy", Start: 0, End: 7}},
+		},
+		{
+			name: "Multiple tags",
+			body: "#go and #sql_c",
+			want: []Entity{
+				{Text: "go", Start: 0, End: 3},
+				{Text: "sql_c", Start: 8, End: 14},
+			},
+		},
+		{
+			name: "Offsets count code points",
+			body: "café #über",
+			want: []Entity{{Text: "über", Start: 5, End: 10}},
+		},
+		{
+			name: "Digits only is not a tag",
+			body: "We're #1",
+			want: nil,
+		},
+		{
+			name: "Hash inside a word is not a tag",
+			body: "C#sharp and a#b",
+			want: nil,
+		},
+		{
+			name: "Lone hash",
+			body: "# nothing",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hashtags(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hashtags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "GoLang", want: "golang"},
+		{tag: "#Chirpy", want: "chirpy"},
+		{tag: "ÜBER", want: "über"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := NormalizeHashtag(tt.tag); got != tt.want {
+				t.Errorf("NormalizeHashtag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Entity
+	}{
+		{
+			name: "Single mention",
+			body: "hi @alice!",
+			want: []Entity{{Text: "alice", Start: 3, End: 9}},
+		},
+		{
+			name: "Mentions keep their case",
+			body: "@Bob_1 and @carol",
+			want: []Entity{
+				{Text: "Bob_1", Start: 0, End: 6},
+				{Text: "carol", Start: 11, End: 17},
+			},
+		},
+		{
+			name: "Email address is not a mention",
+			body: "mail me at bob@example.com",
+			want: nil,
+		},
+		{
+			name: "Too long to be a handle",
+			body: "@abcdefghijklmnop",
+			want: nil,
+		},
+		{
+			name: "Non-ASCII handle is not a mention",
+			body: "@élodie",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mentions(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mentions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidHandle(t *testing.T) {
+	tests := []struct {
+		handle string
+		want   bool
+	}{
+		{handle: "alice", want: true},
+		{handle: "Bob_99", want: true},
+		{handle: "abcdefghijklmno", want: true},
+		{handle: "abcdefghijklmnop", want: false},
+		{handle: "", want: false},
+		{handle: "no-dashes", want: false},
+		{handle: "émile", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.handle, func(t *testing.T) {
+			if got := ValidHandle(tt.handle); got != tt.want {
+				t.Errorf("ValidHandle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
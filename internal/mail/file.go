@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer drops each message into a directory as an .eml file, where
+// tests and mail clients can pick it up.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := msg.encode(m.from, now)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(m.dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	// Write under a hidden name first so nothing watching the directory
+	// sees a half-written message.
+	f, err := os.CreateTemp(m.dir, ".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	base := filepath.Base(tmpName)
+	name := now.UTC().Format("20060102T150405.000000000Z") + "-" + base[1:len(base)-len(".tmp")] + ".eml"
+	err = os.Rename(tmpName, filepath.Join(m.dir, name))
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
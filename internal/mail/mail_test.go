@@ -0,0 +1,221 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewLogMailer(log.New(&buf, "", 0))
+
+	err := mailer.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Confirm your email",
+		Body:    "Your token is abc123",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	for _, want := range []string{"user@example.com", "Confirm your email", "abc123"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log output %q doesn't contain %q", buf.String(), want)
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer(dir, "Chirpy <no-reply@chirpy.example>")
+
+	err := mailer.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Vérifiez votre email",
+		Body:    "Your token is abc123",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got %d .eml files (%v), want 1", len(files), err)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got := readMessage(t, f)
+	if got.To != "user@example.com" || got.Subject != "Vérifiez votre email" || got.Body != "Your token is abc123" {
+		t.Errorf("read back %+v", got)
+	}
+}
+
+func TestEncodeRejectsHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{
+			name: "Newline in subject",
+			msg:  Message{To: "user@example.com", Subject: "Hi\r\nBcc: victim@example.com"},
+		},
+		{
+			name: "Newline in recipient",
+			msg:  Message{To: "user@example.com\nBcc: victim@example.com", Subject: "Hi"},
+		},
+		{
+			name: "Invalid recipient",
+			msg:  Message{To: "not an address", Subject: "Hi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.msg.encode("no-reply@chirpy.example", time.Now())
+			if err == nil {
+				t.Error("encode() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan smtpDelivery, 1)
+	go serveSMTP(t, ln, received)
+
+	mailer := NewSMTPMailer(SMTPConfig{
+		Addr: ln.Addr().String(),
+		From: "Chirpy <no-reply@chirpy.example>",
+	})
+	err = mailer.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Confirm your email",
+		Body:    "Your token is abc123\n.\nThat line above is a lone dot.",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	delivery := <-received
+	if delivery.from != "no-reply@chirpy.example" || delivery.to != "user@example.com" {
+		t.Errorf("envelope = %q -> %q", delivery.from, delivery.to)
+	}
+	got := readMessage(t, strings.NewReader(delivery.data))
+	if got.Subject != "Confirm your email" || got.Body != "Your token is abc123\n.\nThat line above is a lone dot." {
+		t.Errorf("read back %+v", got)
+	}
+}
+
+// TestSMTPMailerMailHog delivers through a real SMTP sink when
+// CHIRPY_TEST_SMTP_ADDR points at one, e.g. MailHog on localhost:1025.
+func TestSMTPMailerMailHog(t *testing.T) {
+	addr := os.Getenv("CHIRPY_TEST_SMTP_ADDR")
+	if addr == "" {
+		t.Skip("CHIRPY_TEST_SMTP_ADDR not set")
+	}
+
+	mailer := NewSMTPMailer(SMTPConfig{Addr: addr, From: "no-reply@chirpy.example"})
+	err := mailer.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Chirpy SMTP test",
+		Body:    "If you can read this, SMTP delivery works.",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+}
+
+// readMessage parses an encoded message back into a Message, decoding the
+// subject and body.
+func readMessage(t *testing.T, r io.Reader) Message {
+	t.Helper()
+	parsed, err := mail.ReadMessage(r)
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("decoding subject: %v", err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	return Message{
+		To:      parsed.Header.Get("To"),
+		Subject: subject,
+		Body:    strings.TrimSuffix(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n"),
+	}
+}
+
+type smtpDelivery struct {
+	from, to, data string
+}
+
+// serveSMTP accepts one connection and speaks just enough SMTP to take a
+// single message.
+func serveSMTP(t *testing.T, ln net.Listener, received chan<- smtpDelivery) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+
+	var delivery smtpDelivery
+	text.PrintfLine("220 localhost ESMTP test")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "MAIL":
+			delivery.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			delivery.to = strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 Go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				t.Errorf("reading DATA: %v", err)
+				return
+			}
+			delivery.data = string(data)
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			received <- delivery
+			return
+		default:
+			text.PrintfLine("502 Not implemented")
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// Size is one rendition a cropped image is produced at.
+type Size struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// Variant is an image cropped and scaled to a Size, always JPEG.
+type Variant struct {
+	Size
+	Data []byte
+}
+
+// Crop cuts the largest centred region of data with the aspect ratio of
+// the first size and scales it to every size. The sizes should all share
+// that ratio. Like Process, it sniffs the real type and drops metadata;
+// an animated GIF keeps only its first frame.
+func Crop(data []byte, sizes ...Size) ([]Variant, error) {
+	img, err := decodeStill(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cropped := toRGBA(img)
+	if len(sizes) > 0 {
+		cropped = toRGBA(cropped.SubImage(cropRect(cropped.Bounds(), sizes[0].Width, sizes[0].Height)))
+	}
+
+	variants := make([]Variant, len(sizes))
+	for i, size := range sizes {
+		scaled := resize(cropped, image.Point{X: size.Width, Y: size.Height})
+		var out bytes.Buffer
+		err = jpeg.Encode(&out, flatten(scaled), &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return nil, err
+		}
+		variants[i] = Variant{Size: size, Data: out.Bytes()}
+	}
+	return variants, nil
+}
+
+// cropRect returns the largest rectangle centred in b with the aspect
+// ratio w:h.
+func cropRect(b image.Rectangle, w, h int) image.Rectangle {
+	cw, ch := b.Dx(), b.Dy()
+	if cw*h > ch*w {
+		cw = maxInt(1, ch*w/h)
+	} else {
+		ch = maxInt(1, cw*h/w)
+	}
+	x := b.Min.X + (b.Dx()-cw)/2
+	y := b.Min.Y + (b.Dy()-ch)/2
+	return image.Rect(x, y, x+cw, y+ch)
+}
+
+// decodeStill decodes data as a single upright frame after the same
+// checks Process makes.
+func decodeStill(data []byte) (image.Image, error) {
+	contentType, err := check(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err == nil {
+			img = applyOrientation(img, exifOrientation(data))
+		}
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		// gif.Decode stops after the first frame.
+		var config image.Config
+		config, err = gif.DecodeConfig(bytes.NewReader(data))
+		if err == nil {
+			img, err = gif.Decode(bytes.NewReader(data))
+		}
+		if err == nil {
+			img = firstFrame(img, config)
+		}
+	}
+	if err != nil || img == nil {
+		return nil, ErrUnsupportedType
+	}
+	return img, nil
+}
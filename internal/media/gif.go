@@ -0,0 +1,67 @@
+package media
+
+import (
+	"encoding/binary"
+)
+
+// gifFrames walks the blocks of a GIF without decoding any pixels and
+// returns how many frames it has and their combined area. ok is false if
+// the file is malformed.
+func gifFrames(data []byte) (frames, pixels int, ok bool) {
+	// Header and logical screen descriptor.
+	if len(data) < 13 {
+		return 0, 0, false
+	}
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension: label, then sub-blocks
+			i = skipSubBlocks(data, i+2)
+		case 0x2C: // image descriptor
+			if i+10 > len(data) {
+				return 0, 0, false
+			}
+			w := int(binary.LittleEndian.Uint16(data[i+5:]))
+			h := int(binary.LittleEndian.Uint16(data[i+7:]))
+			flags := data[i+9]
+			frames++
+			pixels += w * h
+			if pixels > MaxPixels || frames > MaxFrames {
+				// No need to read further; the caller refuses it anyway.
+				return frames, pixels, true
+			}
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			// LZW minimum code size, then the compressed sub-blocks.
+			i = skipSubBlocks(data, i+1)
+		case 0x3B: // trailer
+			return frames, pixels, true
+		default:
+			return 0, 0, false
+		}
+		if i < 0 {
+			return 0, 0, false
+		}
+	}
+	return 0, 0, false
+}
+
+// skipSubBlocks returns the offset just past the run of sub-blocks that
+// starts at i, or -1 if it runs off the end of data.
+func skipSubBlocks(data []byte, i int) int {
+	for i < len(data) {
+		size := int(data[i])
+		i++
+		if size == 0 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
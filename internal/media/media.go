@@ -0,0 +1,242 @@
+// Package media turns uploaded images into what Chirpy stores: the image
+// re-encoded without its metadata, a thumbnail and a BlurHash placeholder.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxPixels bounds the decoded size of an image, since a small file
+	// can still decompress into an enormous bitmap. For an animated GIF it
+	// bounds all the frames together.
+	MaxPixels = 40_000_000
+
+	// MaxFrames bounds the number of frames in an animated GIF.
+	MaxFrames = 500
+
+	// ThumbnailSize is the largest width or height of a thumbnail.
+	ThumbnailSize = 400
+
+	jpegQuality      = 90
+	thumbnailQuality = 80
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrImageTooLarge   = errors.New("image dimensions too large")
+)
+
+// Image is a processed upload.
+type Image struct {
+	ContentType string
+	// Data is the image re-encoded in its original format, which leaves
+	// EXIF and other metadata behind.
+	Data      []byte
+	Width     int
+	Height    int
+	Thumbnail []byte // always JPEG
+	BlurHash  string
+}
+
+// Extension returns the file extension for a content type Process accepts.
+func Extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
+
+// Process sniffs the real type of data, ignoring whatever the client
+// claimed, and prepares it for storage. JPEG orientation from EXIF is
+// applied to the pixels before the EXIF is dropped.
+func Process(data []byte) (*Image, error) {
+	contentType, err := check(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedType
+		}
+		img = applyOrientation(img, exifOrientation(data))
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedType
+		}
+		err = png.Encode(&out, img)
+	case "image/gif":
+		var g *gif.GIF
+		g, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(g.Image) == 0 {
+			return nil, ErrUnsupportedType
+		}
+		img = firstFrame(g.Image[0], g.Config)
+		// Only frames, timing and the loop count survive; comments and
+		// application extensions are not written back.
+		err = gif.EncodeAll(&out, &gif.GIF{
+			Image:           g.Image,
+			Delay:           g.Delay,
+			LoopCount:       g.LoopCount,
+			Disposal:        g.Disposal,
+			Config:          g.Config,
+			BackgroundIndex: g.BackgroundIndex,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rgba := toRGBA(img)
+	thumb := resize(rgba, fit(rgba.Bounds().Dx(), rgba.Bounds().Dy(), ThumbnailSize))
+	var thumbOut bytes.Buffer
+	err = jpeg.Encode(&thumbOut, flatten(thumb), &jpeg.Options{Quality: thumbnailQuality})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		ContentType: contentType,
+		Data:        out.Bytes(),
+		Width:       rgba.Bounds().Dx(),
+		Height:      rgba.Bounds().Dy(),
+		Thumbnail:   thumbOut.Bytes(),
+		BlurHash:    BlurHash(thumb),
+	}, nil
+}
+
+// check sniffs the type of data and makes sure it is an image Process
+// accepts, of a size it is willing to decode.
+func check(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if Extension(contentType) == "" {
+		return "", ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedType
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return "", ErrImageTooLarge
+	}
+	// DecodeConfig only looks at the first frame, but decoding a GIF
+	// allocates every frame.
+	if contentType == "image/gif" {
+		frames, pixels, ok := gifFrames(data)
+		if !ok {
+			return "", ErrUnsupportedType
+		}
+		if frames > MaxFrames || pixels > MaxPixels {
+			return "", ErrImageTooLarge
+		}
+	}
+	return contentType, nil
+}
+
+// firstFrame draws a GIF's first frame onto a canvas the size of the
+// logical screen.
+func firstFrame(frame image.Image, config image.Config) image.Image {
+	bounds := image.Rect(0, 0, config.Width, config.Height)
+	if bounds.Empty() {
+		return frame
+	}
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	return canvas
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// flatten composites img onto white, for formats without transparency.
+func flatten(img *image.RGBA) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Over)
+	return out
+}
+
+// fit scales w x h down, keeping its aspect ratio, until neither side is
+// over max. Images already small enough keep their size.
+func fit(w, h, max int) image.Point {
+	if w <= max && h <= max {
+		return image.Point{X: w, Y: h}
+	}
+	if w >= h {
+		return image.Point{X: max, Y: maxInt(1, h*max/w)}
+	}
+	return image.Point{X: maxInt(1, w*max/h), Y: max}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// resize scales src to size by averaging the source pixels that fall
+// under each destination pixel, which is cheap and doesn't alias when
+// shrinking.
+func resize(src *image.RGBA, size image.Point) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if size.X == sw && size.Y == sh {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	for y := 0; y < size.Y; y++ {
+		y0 := y * sh / size.Y
+		y1 := maxInt(y0+1, (y+1)*sh/size.Y)
+		for x := 0; x < size.X; x++ {
+			x0 := x * sw / size.X
+			x1 := maxInt(x0+1, (x+1)*sw/size.X)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,401 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func solid(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// withEXIFOrientation splices an APP1 segment carrying only an
+// Orientation tag in after a JPEG's SOI marker.
+func withEXIFOrientation(jpg []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, app1...)
+	return append(out, jpg[2:]...)
+}
+
+// withPNGText adds a tEXt chunk after a PNG's IHDR chunk.
+func withPNGText(p []byte, text string) []byte {
+	data := []byte("Comment\x00" + text)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	typed := append([]byte("tEXt"), data...)
+	chunk = append(chunk, typed...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(typed))
+
+	ihdrEnd := 8 + 4 + 4 + 13 + 4
+	out := append([]byte{}, p[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, p[ihdrEnd:]...)
+}
+
+func TestProcess(t *testing.T) {
+	var jpg bytes.Buffer
+	jpeg.Encode(&jpg, solid(60, 20, color.RGBA{200, 30, 30, 255}), nil)
+
+	var pngBuf bytes.Buffer
+	png.Encode(&pngBuf, solid(800, 200, color.RGBA{0, 0, 255, 255}))
+
+	var gifBuf bytes.Buffer
+	frame := image.NewPaletted(image.Rect(0, 0, 10, 10), color.Palette{color.Black, color.White})
+	gif.EncodeAll(&gifBuf, &gif.GIF{Image: []*image.Paletted{frame, frame}, Delay: []int{10, 10}})
+
+	tests := []struct {
+		name            string
+		data            []byte
+		wantType        string
+		wantWidth       int
+		wantHeight      int
+		wantThumbWidth  int
+		wantThumbHeight int
+		forbidden       string
+		wantErr         error
+	}{
+		{
+			name:            "JPEG turned upright and EXIF dropped",
+			data:            withEXIFOrientation(jpg.Bytes(), 6),
+			wantType:        "image/jpeg",
+			wantWidth:       20,
+			wantHeight:      60,
+			wantThumbWidth:  20,
+			wantThumbHeight: 60,
+			forbidden:       "Exif",
+		},
+		{
+			name:            "PNG text chunks dropped and thumbnail shrunk",
+			data:            withPNGText(pngBuf.Bytes(), "secret location"),
+			wantType:        "image/png",
+			wantWidth:       800,
+			wantHeight:      200,
+			wantThumbWidth:  400,
+			wantThumbHeight: 100,
+			forbidden:       "secret location",
+		},
+		{
+			name:            "Animated GIF keeps its frames",
+			data:            gifBuf.Bytes(),
+			wantType:        "image/gif",
+			wantWidth:       10,
+			wantHeight:      10,
+			wantThumbWidth:  10,
+			wantThumbHeight: 10,
+		},
+		{
+			name:    "Not an image",
+			data:    []byte("<html><body>hi</body></html>"),
+			wantErr: ErrUnsupportedType,
+		},
+		{
+			name:    "Truncated image",
+			data:    pngBuf.Bytes()[:40],
+			wantErr: ErrUnsupportedType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Process(tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Process() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			if img.ContentType != tt.wantType {
+				t.Errorf("ContentType = %q, want %q", img.ContentType, tt.wantType)
+			}
+			if img.Width != tt.wantWidth || img.Height != tt.wantHeight {
+				t.Errorf("size = %dx%d, want %dx%d", img.Width, img.Height, tt.wantWidth, tt.wantHeight)
+			}
+			config, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+			if err != nil || config.Width != tt.wantWidth || config.Height != tt.wantHeight {
+				t.Errorf("stored image = %dx%d, %v", config.Width, config.Height, err)
+			}
+			if tt.forbidden != "" && bytes.Contains(img.Data, []byte(tt.forbidden)) {
+				t.Errorf("stored image still contains %q", tt.forbidden)
+			}
+
+			thumb, err := jpeg.DecodeConfig(bytes.NewReader(img.Thumbnail))
+			if err != nil || thumb.Width != tt.wantThumbWidth || thumb.Height != tt.wantThumbHeight {
+				t.Errorf("thumbnail = %dx%d, %v, want %dx%d", thumb.Width, thumb.Height, err, tt.wantThumbWidth, tt.wantThumbHeight)
+			}
+			if len(img.BlurHash) != 28 {
+				t.Errorf("BlurHash = %q, want 28 characters", img.BlurHash)
+			}
+
+			if tt.wantType == "image/gif" {
+				g, err := gif.DecodeAll(bytes.NewReader(img.Data))
+				if err != nil || len(g.Image) != 2 {
+					t.Errorf("stored GIF has %d frames, %v, want 2", len(g.Image), err)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessRejectsHugeDimensions(t *testing.T) {
+	// A PNG header claiming 10000x10000 pixels; Process must refuse it
+	// before trying to decode.
+	var buf bytes.Buffer
+	png.Encode(&buf, solid(1, 1, color.White))
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 10000)
+	binary.BigEndian.PutUint32(data[20:], 10000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	_, err := Process(data)
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Process() error = %v, want %v", err, ErrImageTooLarge)
+	}
+}
+
+func TestRejectsHugeGIFs(t *testing.T) {
+	// Each frame passes the first-frame check on its own; together they
+	// are too much to decode.
+	animation := func(frames, size int) []byte {
+		frame := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.Black, color.White})
+		g := &gif.GIF{}
+		for i := 0; i < frames; i++ {
+			g.Image = append(g.Image, frame)
+			g.Delay = append(g.Delay, 10)
+		}
+		var buf bytes.Buffer
+		gif.EncodeAll(&buf, g)
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "Too many frames", data: animation(MaxFrames+1, 1)},
+		{name: "Too many pixels", data: animation(5, 3000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(tt.data)
+			if !errors.Is(err, ErrImageTooLarge) {
+				t.Errorf("Process() error = %v, want %v", err, ErrImageTooLarge)
+			}
+			_, err = Crop(tt.data, Size{Name: "small", Width: 10, Height: 10})
+			if !errors.Is(err, ErrImageTooLarge) {
+				t.Errorf("Crop() error = %v, want %v", err, ErrImageTooLarge)
+			}
+		})
+	}
+}
+
+func TestBlurHash(t *testing.T) {
+	tests := []struct {
+		name         string
+		img          *image.RGBA
+		wantSizeFlag string
+		wantDC       string
+	}{
+		{
+			// 4x3 components: (4-1) + (3-1)*9 = 21.
+			name:         "Landscape white",
+			img:          solid(8, 6, color.White),
+			wantSizeFlag: "L",
+			wantDC:       "TSUA",
+		},
+		{
+			// 3x4 components: (3-1) + (4-1)*9 = 29.
+			name:         "Portrait black",
+			img:          solid(6, 8, color.Black),
+			wantSizeFlag: "T",
+			wantDC:       "0000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BlurHash(tt.img)
+			if len(got) != 28 {
+				t.Fatalf("BlurHash() = %q, want 28 characters", got)
+			}
+			if got[:1] != tt.wantSizeFlag {
+				t.Errorf("BlurHash() size flag = %q, want %q", got[:1], tt.wantSizeFlag)
+			}
+			if got[2:6] != tt.wantDC {
+				t.Errorf("BlurHash() DC = %q, want %q", got[2:6], tt.wantDC)
+			}
+		})
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	var jpg bytes.Buffer
+	jpeg.Encode(&jpg, solid(4, 4, color.White), nil)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "No EXIF", data: jpg.Bytes(), want: 1},
+		{name: "Rotated", data: withEXIFOrientation(jpg.Bytes(), 8), want: 8},
+		{name: "Out of range", data: withEXIFOrientation(jpg.Bytes(), 42), want: 1},
+		{name: "Not a JPEG", data: []byte("GIF89a"), want: 1},
+		{name: "Truncated", data: withEXIFOrientation(jpg.Bytes(), 6)[:20], want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exifOrientation(tt.data); got != tt.want {
+				t.Errorf("exifOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// A 2x1 image, red then blue, turned each way.
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		want        [][]color.RGBA
+	}{
+		{orientation: 1, want: [][]color.RGBA{{red, blue}}},
+		{orientation: 2, want: [][]color.RGBA{{blue, red}}},
+		{orientation: 3, want: [][]color.RGBA{{blue, red}}},
+		{orientation: 6, want: [][]color.RGBA{{red}, {blue}}},
+		{orientation: 8, want: [][]color.RGBA{{blue}, {red}}},
+	}
+
+	for _, tt := range tests {
+		got := toRGBA(applyOrientation(src, tt.orientation))
+		for y, row := range tt.want {
+			for x, want := range row {
+				if c := got.RGBAAt(x, y); c != want {
+					t.Errorf("orientation %d: pixel (%d,%d) = %v, want %v", tt.orientation, x, y, c, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCrop(t *testing.T) {
+	// Three 100x100 squares side by side: red, green, blue.
+	wide := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for x := 0; x < 300; x++ {
+		c := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}[x/100]
+		for y := 0; y < 100; y++ {
+			wide.Set(x, y, c)
+		}
+	}
+	var widePNG bytes.Buffer
+	png.Encode(&widePNG, wide)
+
+	var tallJPEG bytes.Buffer
+	jpeg.Encode(&tallJPEG, solid(90, 300, color.White), nil)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		sizes     []Size
+		wantColor color.RGBA
+		wantErr   error
+	}{
+		{
+			name:      "Square from landscape keeps the middle",
+			data:      widePNG.Bytes(),
+			sizes:     []Size{{Name: "large", Width: 64, Height: 64}, {Name: "small", Width: 16, Height: 16}},
+			wantColor: color.RGBA{0, 255, 0, 255},
+		},
+		{
+			name:      "Banner from portrait",
+			data:      tallJPEG.Bytes(),
+			sizes:     []Size{{Name: "large", Width: 150, Height: 50}},
+			wantColor: color.RGBA{255, 255, 255, 255},
+		},
+		{
+			name:    "Not an image",
+			data:    []byte("just text"),
+			sizes:   []Size{{Name: "large", Width: 64, Height: 64}},
+			wantErr: ErrUnsupportedType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, err := Crop(tt.data, tt.sizes...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Crop() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Crop() error = %v", err)
+			}
+			if len(variants) != len(tt.sizes) {
+				t.Fatalf("Crop() returned %d variants, want %d", len(variants), len(tt.sizes))
+			}
+
+			for i, v := range variants {
+				img, err := jpeg.Decode(bytes.NewReader(v.Data))
+				if err != nil {
+					t.Fatalf("variant %s isn't a JPEG: %v", v.Name, err)
+				}
+				size := tt.sizes[i]
+				if img.Bounds().Dx() != size.Width || img.Bounds().Dy() != size.Height {
+					t.Errorf("variant %s = %dx%d, want %dx%d", v.Name, img.Bounds().Dx(), img.Bounds().Dy(), size.Width, size.Height)
+				}
+				// JPEG is lossy, so only check the corners are close.
+				for _, p := range []image.Point{{0, 0}, {size.Width - 1, size.Height - 1}} {
+					r, g, b, _ := img.At(p.X, p.Y).RGBA()
+					if diff(r>>8, tt.wantColor.R) > 24 || diff(g>>8, tt.wantColor.G) > 24 || diff(b>>8, tt.wantColor.B) > 24 {
+						t.Errorf("variant %s pixel %v = (%d,%d,%d), want about %v", v.Name, p, r>>8, g>>8, b>>8, tt.wantColor)
+					}
+				}
+			}
+		})
+	}
+}
+
+func diff(a uint32, b uint8) uint32 {
+	if a > uint32(b) {
+		return a - uint32(b)
+	}
+	return uint32(b) - a
+}
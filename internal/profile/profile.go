@@ -0,0 +1,125 @@
+// Package profile validates the public fields of a user's profile.
+package profile
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/mjossany/Chirpy/internal/entities"
+)
+
+const (
+	MaxDisplayNameLength = 50
+	MaxBioLength         = 160
+	MaxWebsiteLength     = 100
+)
+
+var (
+	ErrInvalidHandle      = errors.New("handle must be 1-15 letters, digits or underscores")
+	ErrReservedHandle     = errors.New("handle is reserved")
+	ErrDisplayNameTooLong = errors.New("display name is too long")
+	ErrBioTooLong         = errors.New("bio is too long")
+	ErrInvalidWebsite     = errors.New("website must be an http or https URL")
+)
+
+// reservedHandles can't be claimed: they name parts of the app or would
+// let someone pass for staff. Compared lower-cased.
+var reservedHandles = map[string]bool{
+	"2fa":           true,
+	"about":         true,
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"app":           true,
+	"bookmarks":     true,
+	"chirpy":        true,
+	"everyone":      true,
+	"explore":       true,
+	"help":          true,
+	"here":          true,
+	"home":          true,
+	"login":         true,
+	"logout":        true,
+	"me":            true,
+	"moderator":     true,
+	"notifications": true,
+	"null":          true,
+	"official":      true,
+	"polka":         true,
+	"root":          true,
+	"search":        true,
+	"security":      true,
+	"settings":      true,
+	"signup":        true,
+	"staff":         true,
+	"support":       true,
+	"system":        true,
+	"undefined":     true,
+}
+
+// ValidateHandle checks a handle someone wants to claim.
+func ValidateHandle(handle string) error {
+	if !entities.ValidHandle(handle) {
+		return ErrInvalidHandle
+	}
+	if reservedHandles[entities.NormalizeHandle(handle)] {
+		return ErrReservedHandle
+	}
+	return nil
+}
+
+// CleanDisplayName trims a display name and turns any run of whitespace or
+// control characters in it into a single space.
+func CleanDisplayName(name string) (string, error) {
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	if len([]rune(name)) > MaxDisplayNameLength {
+		return "", ErrDisplayNameTooLong
+	}
+	return name, nil
+}
+
+// CleanBio trims a bio. Line breaks are kept; other control characters
+// are dropped.
+func CleanBio(bio string) (string, error) {
+	bio = strings.Map(func(r rune) rune {
+		if r != '\n' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(bio))
+	if len([]rune(bio)) > MaxBioLength {
+		return "", ErrBioTooLong
+	}
+	return bio, nil
+}
+
+// CleanWebsite checks a website link. A link without a scheme is taken to
+// be https; an empty one clears the field.
+func CleanWebsite(website string) (string, error) {
+	website = strings.TrimSpace(website)
+	if website == "" {
+		return "", nil
+	}
+	if !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+	if len(website) > MaxWebsiteLength {
+		return "", ErrInvalidWebsite
+	}
+
+	u, err := url.Parse(website)
+	if err != nil {
+		return "", ErrInvalidWebsite
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return "", ErrInvalidWebsite
+	}
+	if !strings.Contains(u.Hostname(), ".") {
+		return "", ErrInvalidWebsite
+	}
+	return u.String(), nil
+}
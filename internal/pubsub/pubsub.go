@@ -0,0 +1,153 @@
+// Package pubsub fans events out to in-process subscribers and keeps a
+// short history of them so a subscriber that reconnects can pick up where
+// it left off.
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is a single published message. IDs increase strictly across the
+// life of a Broker and, because they are seeded from the clock, across
+// restarts too.
+type Event struct {
+	ID     uint64
+	Type   string
+	Topics []string
+	Data   []byte
+}
+
+// HasTopic reports whether the event was published to topic.
+func (e Event) HasTopic(topic string) bool {
+	for _, t := range e.Topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// Broker delivers published events to every subscriber whose filter
+// matches them.
+type Broker struct {
+	mu         sync.Mutex
+	lastID     uint64
+	history    []Event
+	start      int
+	bufferSize int
+	subs       map[*Subscription]struct{}
+}
+
+// NewBroker returns a Broker that remembers the last historySize events
+// and buffers up to bufferSize undelivered events per subscriber.
+func NewBroker(historySize, bufferSize int) *Broker {
+	return &Broker{
+		lastID:     uint64(time.Now().UnixMicro()),
+		history:    make([]Event, 0, historySize),
+		bufferSize: bufferSize,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the next ID to an event and hands it to every matching
+// subscriber. It never blocks: a subscriber whose buffer is full is
+// dropped, and can resubscribe from the last event it saw.
+func (b *Broker) Publish(eventType string, data []byte, topics ...string) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{
+		ID:     b.lastID,
+		Type:   eventType,
+		Topics: topics,
+		Data:   data,
+	}
+
+	if len(b.history) < cap(b.history) {
+		b.history = append(b.history, event)
+	} else if cap(b.history) > 0 {
+		b.history[b.start] = event
+		b.start = (b.start + 1) % cap(b.history)
+	}
+
+	for sub := range b.subs {
+		if !sub.match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.drop(sub)
+		}
+	}
+	return event
+}
+
+// Subscribe registers a subscriber for events that match, or for every
+// event when match is nil. When lastID is non-zero the matching events
+// published after it are returned for replay; complete is false if some of
+// them have already fallen out of the history.
+func (b *Broker) Subscribe(lastID uint64, match func(Event) bool) (sub *Subscription, replay []Event, complete bool) {
+	if match == nil {
+		match = func(Event) bool { return true }
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastID != 0 {
+		oldest := b.lastID + 1
+		if len(b.history) > 0 {
+			oldest = b.history[b.start].ID
+		}
+		if lastID+1 < oldest || lastID > b.lastID {
+			complete = false
+		}
+		for i := range b.history {
+			event := b.history[(b.start+i)%len(b.history)]
+			if event.ID > lastID && match(event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	sub = &Subscription{
+		broker: b,
+		events: make(chan Event, b.bufferSize),
+		match:  match,
+	}
+	b.subs[sub] = struct{}{}
+	return sub, replay, complete
+}
+
+// drop unregisters sub and closes its channel. b.mu must be held.
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.events)
+}
+
+// Subscription is one subscriber's view of a Broker.
+type Subscription struct {
+	broker *Broker
+	events chan Event
+	match  func(Event) bool
+}
+
+// Events delivers matching events in order. It is closed when the
+// subscription is closed or dropped for falling behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops delivery. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.drop(s)
+}
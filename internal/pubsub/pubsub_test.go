@@ -0,0 +1,132 @@
+package pubsub
+
+import (
+	"testing"
+)
+
+func TestPublishDeliversMatchingEvents(t *testing.T) {
+	broker := NewBroker(10, 10)
+	sub, _, _ := broker.Subscribe(0, func(e Event) bool {
+		return e.HasTopic("chirps")
+	})
+	defer sub.Close()
+
+	broker.Publish("chirp", []byte("a"), "chirps")
+	broker.Publish("notification", []byte("b"), "notifications:someone")
+	broker.Publish("chirp", []byte("c"), "chirps")
+
+	for _, want := range []string{"a", "c"} {
+		select {
+		case event := <-sub.Events():
+			if string(event.Data) != want {
+				t.Errorf("Events() got %q, want %q", event.Data, want)
+			}
+		default:
+			t.Fatalf("Events() missing %q", want)
+		}
+	}
+	select {
+	case event := <-sub.Events():
+		t.Errorf("Events() got unexpected %q", event.Data)
+	default:
+	}
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	broker := NewBroker(3, 10)
+	var ids []uint64
+	for _, data := range []string{"a", "b", "c", "d", "e"} {
+		ids = append(ids, broker.Publish("chirp", []byte(data)).ID)
+	}
+
+	tests := []struct {
+		name         string
+		lastID       uint64
+		wantReplay   []string
+		wantComplete bool
+	}{
+		{
+			name:         "No last event",
+			lastID:       0,
+			wantReplay:   nil,
+			wantComplete: true,
+		},
+		{
+			name:         "Within history",
+			lastID:       ids[2],
+			wantReplay:   []string{"d", "e"},
+			wantComplete: true,
+		},
+		{
+			name:         "Just before oldest retained",
+			lastID:       ids[1],
+			wantReplay:   []string{"c", "d", "e"},
+			wantComplete: true,
+		},
+		{
+			name:         "Older than history",
+			lastID:       ids[0],
+			wantReplay:   []string{"c", "d", "e"},
+			wantComplete: false,
+		},
+		{
+			name:         "Up to date",
+			lastID:       ids[4],
+			wantReplay:   nil,
+			wantComplete: true,
+		},
+		{
+			name:         "From the future",
+			lastID:       ids[4] + 100,
+			wantReplay:   nil,
+			wantComplete: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, replay, complete := broker.Subscribe(tt.lastID, nil)
+			defer sub.Close()
+
+			if complete != tt.wantComplete {
+				t.Errorf("Subscribe() complete = %v, want %v", complete, tt.wantComplete)
+			}
+			if len(replay) != len(tt.wantReplay) {
+				t.Fatalf("Subscribe() replayed %d events, want %d", len(replay), len(tt.wantReplay))
+			}
+			for i, event := range replay {
+				if string(event.Data) != tt.wantReplay[i] {
+					t.Errorf("Subscribe() replay[%d] = %q, want %q", i, event.Data, tt.wantReplay[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	broker := NewBroker(10, 2)
+	slow, _, _ := broker.Subscribe(0, nil)
+	fast, _, _ := broker.Subscribe(0, nil)
+	defer fast.Close()
+
+	for i := 0; i < 3; i++ {
+		broker.Publish("chirp", nil)
+		<-fast.Events()
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("slow subscriber received %d events before being dropped, want 2", received)
+	}
+
+	broker.Publish("chirp", nil)
+	if _, ok := <-fast.Events(); !ok {
+		t.Error("fast subscriber was dropped")
+	}
+
+	// Closing an already dropped subscription is a no-op.
+	slow.Close()
+}
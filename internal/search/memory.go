@@ -0,0 +1,216 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"html"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/entities"
+)
+
+// Document is a chirp as MemoryIndex sees it.
+type Document struct {
+	ChirpID      uuid.UUID
+	AuthorID     uuid.UUID
+	AuthorHandle string
+	Body         string
+	CreatedAt    time.Time
+}
+
+// MemoryIndex is a Searcher over documents held in memory. It matches
+// whole words case-insensitively, without the stemming and stop words of
+// Postgres, which is close enough for tests.
+type MemoryIndex struct {
+	mu   sync.RWMutex
+	docs map[uuid.UUID]Document
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{docs: make(map[uuid.UUID]Document)}
+}
+
+// Add indexes doc, replacing any earlier version of the same chirp.
+func (m *MemoryIndex) Add(doc Document) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.docs[doc.ChirpID] = doc
+}
+
+func (m *MemoryIndex) Remove(chirpID uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.docs, chirpID)
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, q Query, limit, offset int) ([]Result, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type match struct {
+		doc    Document
+		result Result
+	}
+	var matches []match
+	for _, doc := range m.docs {
+		result, ok := matchDocument(doc, q)
+		if ok {
+			matches = append(matches, match{doc: doc, result: result})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b match) int {
+		if c := cmp.Compare(b.result.Rank, a.result.Rank); c != 0 {
+			return c
+		}
+		if c := b.doc.CreatedAt.Compare(a.doc.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.doc.ChirpID.String(), a.doc.ChirpID.String())
+	})
+
+	if offset >= len(matches) {
+		return []Result{}, nil
+	}
+	matches = matches[offset:]
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	results := make([]Result, len(matches))
+	for i, m := range matches {
+		results[i] = m.result
+	}
+	return results, nil
+}
+
+// word is a run of letters and digits in a body, at rune offsets
+// [start, end).
+type word struct {
+	text       string
+	start, end int
+}
+
+func splitWords(s string) []word {
+	var words []word
+	runes := []rune(s)
+	start := -1
+	for i := 0; i <= len(runes); i++ {
+		inWord := i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]))
+		if inWord && start < 0 {
+			start = i
+		}
+		if !inWord && start >= 0 {
+			words = append(words, word{text: strings.ToLower(string(runes[start:i])), start: start, end: i})
+			start = -1
+		}
+	}
+	return words
+}
+
+func matchDocument(doc Document, q Query) (Result, bool) {
+	if q.FromID.Valid && doc.AuthorID != q.FromID.UUID {
+		return Result{}, false
+	}
+	if q.FromHandle != "" && entities.NormalizeHandle(doc.AuthorHandle) != q.FromHandle {
+		return Result{}, false
+	}
+	if q.Since != nil && doc.CreatedAt.Before(*q.Since) {
+		return Result{}, false
+	}
+	if q.Until != nil && !doc.CreatedAt.Before(*q.Until) {
+		return Result{}, false
+	}
+
+	if len(q.Tags) > 0 {
+		tags := make(map[string]bool)
+		for _, hashtag := range entities.Hashtags(doc.Body) {
+			tags[entities.NormalizeHashtag(hashtag.Text)] = true
+		}
+		for _, tag := range q.Tags {
+			if !tags[tag] {
+				return Result{}, false
+			}
+		}
+	}
+
+	words := splitWords(doc.Body)
+	highlighted := make([]bool, len(words))
+	hits := 0
+
+	for _, term := range q.Terms {
+		found := false
+		for _, termWord := range splitWords(term) {
+			for i, w := range words {
+				if w.text == termWord.text {
+					highlighted[i] = true
+					found = true
+					hits++
+				}
+			}
+		}
+		if !found {
+			return Result{}, false
+		}
+	}
+
+	for _, phrase := range q.Phrases {
+		phraseWords := splitWords(phrase)
+		if len(phraseWords) == 0 {
+			continue
+		}
+		found := false
+		for i := 0; i+len(phraseWords) <= len(words); i++ {
+			matched := true
+			for j, pw := range phraseWords {
+				if words[i+j].text != pw.text {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				for j := range phraseWords {
+					highlighted[i+j] = true
+				}
+				found = true
+				hits += len(phraseWords)
+			}
+		}
+		if !found {
+			return Result{}, false
+		}
+	}
+
+	rank := 0.0
+	if len(words) > 0 {
+		rank = float64(hits) / float64(len(words))
+	}
+	return Result{
+		ChirpID: doc.ChirpID,
+		Rank:    rank,
+		Snippet: highlight(doc.Body, words, highlighted),
+	}, true
+}
+
+func highlight(body string, words []word, highlighted []bool) string {
+	runes := []rune(body)
+	var b strings.Builder
+	last := 0
+	for i, w := range words {
+		if !highlighted[i] {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[last:w.start])))
+		b.WriteString(HighlightStart)
+		b.WriteString(html.EscapeString(string(runes[w.start:w.end])))
+		b.WriteString(HighlightStop)
+		last = w.end
+	}
+	b.WriteString(html.EscapeString(string(runes[last:])))
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryIndexSearch(t *testing.T) {
+	alice := uuid.New()
+	bob := uuid.New()
+	carol := uuid.New()
+	day := func(d int) time.Time {
+		return time.Date(2025, 1, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	docs := []Document{
+		{ChirpID: uuid.New(), AuthorID: alice, AuthorHandle: "alice", Body: "Learning Go today #golang", CreatedAt: day(1)},
+		{ChirpID: uuid.New(), AuthorID: bob, AuthorHandle: "Bob", Body: "go go go", CreatedAt: day(2)},
+		{ChirpID: uuid.New(), AuthorID: alice, AuthorHandle: "alice", Body: "The gopher party was fun", CreatedAt: day(3)},
+		{ChirpID: uuid.New(), AuthorID: bob, AuthorHandle: "Bob", Body: "A party for every gopher #golang", CreatedAt: day(4)},
+		{ChirpID: uuid.New(), AuthorID: carol, AuthorHandle: "carol", Body: `<script>alert("hi & bye")</script>`, CreatedAt: day(5)},
+	}
+	index := NewMemoryIndex()
+	for _, doc := range docs {
+		index.Add(doc)
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		want        []uuid.UUID
+		wantSnippet string
+	}{
+		{
+			name:        "Ranked by match density",
+			query:       "go",
+			want:        []uuid.UUID{docs[1].ChirpID, docs[0].ChirpID},
+			wantSnippet: "<mark>go</mark> <mark>go</mark> <mark>go</mark>",
+		},
+		{
+			name:        "Phrase keeps word order",
+			query:       `"gopher party"`,
+			want:        []uuid.UUID{docs[2].ChirpID},
+			wantSnippet: "The <mark>gopher</mark> <mark>party</mark> was fun",
+		},
+		{
+			name:  "Every term must match",
+			query: "gopher party",
+			want:  []uuid.UUID{docs[2].ChirpID, docs[3].ChirpID},
+		},
+		{
+			name:        "Tag only, newest first",
+			query:       "#GoLang",
+			want:        []uuid.UUID{docs[3].ChirpID, docs[0].ChirpID},
+			wantSnippet: "A party for every gopher #golang",
+		},
+		{
+			name:  "From handle ignores case",
+			query: "from:bob",
+			want:  []uuid.UUID{docs[3].ChirpID, docs[1].ChirpID},
+		},
+		{
+			name:  "From user ID",
+			query: "from:" + alice.String() + " gopher",
+			want:  []uuid.UUID{docs[2].ChirpID},
+		},
+		{
+			name:  "Date range",
+			query: "since:2025-01-02 until:2025-01-04",
+			want:  []uuid.UUID{docs[2].ChirpID, docs[1].ChirpID},
+		},
+		{
+			name:        "Snippet is escaped HTML",
+			query:       "alert",
+			want:        []uuid.UUID{docs[4].ChirpID},
+			wantSnippet: "&lt;script&gt;<mark>alert</mark>(&#34;hi &amp; bye&#34;)&lt;/script&gt;",
+		},
+		{
+			name:  "No match",
+			query: "rust",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			results, err := index.Search(context.Background(), q, 10, 0)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(results) != len(tt.want) {
+				t.Fatalf("Search() returned %d results, want %d", len(results), len(tt.want))
+			}
+			for i, result := range results {
+				if result.ChirpID != tt.want[i] {
+					t.Errorf("Search() result %d = %v, want %v", i, result.ChirpID, tt.want[i])
+				}
+			}
+			if tt.wantSnippet != "" && results[0].Snippet != tt.wantSnippet {
+				t.Errorf("Search() snippet = %q, want %q", results[0].Snippet, tt.wantSnippet)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexPaging(t *testing.T) {
+	index := NewMemoryIndex()
+	var ids []uuid.UUID
+	for i := 0; i < 5; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		index.Add(Document{ChirpID: id, Body: "chirp", CreatedAt: time.Date(2025, 1, 5-i, 0, 0, 0, 0, time.UTC)})
+	}
+	index.Remove(ids[1])
+
+	q, _ := ParseQuery("chirp")
+	page, _ := index.Search(context.Background(), q, 2, 2)
+	if len(page) != 2 || page[0].ChirpID != ids[3] || page[1].ChirpID != ids[4] {
+		t.Errorf("Search() second page = %+v, want %v and %v", page, ids[3], ids[4])
+	}
+
+	page, _ = index.Search(context.Background(), q, 2, 10)
+	if len(page) != 0 {
+		t.Errorf("Search() past the end returned %d results", len(page))
+	}
+}
@@ -0,0 +1,55 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+// PostgresSearcher searches chirps through the chirp_search_idx full-text
+// index, ranking with ts_rank_cd and highlighting with ts_headline.
+type PostgresSearcher struct {
+	db *database.Queries
+}
+
+func NewPostgresSearcher(db *database.Queries) *PostgresSearcher {
+	return &PostgresSearcher{db: db}
+}
+
+func (s *PostgresSearcher) Search(ctx context.Context, q Query, limit, offset int) ([]Result, error) {
+	params := database.SearchChirpsParams{
+		Query:      q.Text(),
+		AuthorID:   q.FromID,
+		Tags:       q.Tags,
+		PageSize:   int32(limit),
+		PageOffset: int32(offset),
+	}
+	if params.Tags == nil {
+		params.Tags = []string{}
+	}
+	if q.FromHandle != "" {
+		params.AuthorHandle = sql.NullString{String: q.FromHandle, Valid: true}
+	}
+	if q.Since != nil {
+		params.Since = sql.NullTime{Time: *q.Since, Valid: true}
+	}
+	if q.Until != nil {
+		params.Until = sql.NullTime{Time: *q.Until, Valid: true}
+	}
+
+	rows, err := s.db.SearchChirps(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(rows))
+	for i, row := range rows {
+		results[i] = Result{
+			ChirpID: row.ID,
+			Rank:    float64(row.Rank),
+			Snippet: row.Snippet,
+		}
+	}
+	return results, nil
+}
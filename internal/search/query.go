@@ -0,0 +1,141 @@
+package search
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/entities"
+)
+
+const dateLayout = "2006-01-02"
+
+// Query is a parsed search. Terms and Phrases must all appear in the body;
+// the other fields narrow the results down further.
+type Query struct {
+	Terms   []string
+	Phrases []string
+	Tags    []string
+	// From is set to the author's ID when from: named a UUID and to their
+	// handle otherwise.
+	FromID     uuid.NullUUID
+	FromHandle string
+	// Since is inclusive and Until exclusive, both at midnight UTC.
+	Since *time.Time
+	Until *time.Time
+}
+
+// ParseQuery splits a search string into words, "quoted phrases" and the
+// operators from:<handle or user ID>, since:<YYYY-MM-DD>,
+// until:<YYYY-MM-DD> and #tag.
+func ParseQuery(s string) (Query, error) {
+	q := Query{}
+
+	for _, token := range tokenize(s) {
+		if token.quoted {
+			if words := strings.Fields(token.text); len(words) > 0 {
+				q.Phrases = append(q.Phrases, strings.Join(words, " "))
+			}
+			continue
+		}
+
+		text := token.text
+		switch {
+		case strings.HasPrefix(text, "from:"):
+			from := strings.TrimPrefix(strings.TrimPrefix(text, "from:"), "@")
+			if from == "" {
+				return q, errors.New("from: needs a user")
+			}
+			if id, err := uuid.Parse(from); err == nil {
+				q.FromID = uuid.NullUUID{UUID: id, Valid: true}
+				q.FromHandle = ""
+			} else {
+				q.FromHandle = entities.NormalizeHandle(from)
+				q.FromID = uuid.NullUUID{}
+			}
+		case strings.HasPrefix(text, "since:"):
+			t, err := time.Parse(dateLayout, strings.TrimPrefix(text, "since:"))
+			if err != nil {
+				return q, errors.New("since: must be a date like 2025-01-31")
+			}
+			q.Since = &t
+		case strings.HasPrefix(text, "until:"):
+			t, err := time.Parse(dateLayout, strings.TrimPrefix(text, "until:"))
+			if err != nil {
+				return q, errors.New("until: must be a date like 2025-01-31")
+			}
+			q.Until = &t
+		case strings.HasPrefix(text, "#") && len(text) > 1:
+			// Postgres counts the tags a chirp matches, so each may only
+			// appear once.
+			tag := entities.NormalizeHashtag(text)
+			if !slices.Contains(q.Tags, tag) {
+				q.Tags = append(q.Tags, tag)
+			}
+		default:
+			q.Terms = append(q.Terms, text)
+		}
+	}
+
+	if q.Empty() {
+		return q, errors.New("search query is empty")
+	}
+	return q, nil
+}
+
+// Empty reports whether the query has nothing to search by.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && len(q.Tags) == 0 &&
+		!q.FromID.Valid && q.FromHandle == "" && q.Since == nil && q.Until == nil
+}
+
+// Text is the full-text part of the query in Postgres websearch_to_tsquery
+// syntax: bare terms and double-quoted phrases.
+func (q Query) Text() string {
+	parts := make([]string, 0, len(q.Terms)+len(q.Phrases))
+	for _, term := range q.Terms {
+		// Quotes would open a phrase in websearch syntax.
+		parts = append(parts, strings.ReplaceAll(term, `"`, ""))
+	}
+	for _, phrase := range q.Phrases {
+		parts = append(parts, `"`+phrase+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
+type queryToken struct {
+	text   string
+	quoted bool
+}
+
+// tokenize splits on whitespace, keeping double-quoted runs together. An
+// unterminated quote runs to the end of the string.
+func tokenize(s string) []queryToken {
+	var tokens []queryToken
+	var current strings.Builder
+	quoted := false
+
+	flush := func() {
+		if current.Len() > 0 || quoted {
+			tokens = append(tokens, queryToken{text: current.String(), quoted: quoted})
+		}
+		current.Reset()
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			flush()
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
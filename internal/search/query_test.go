@@ -0,0 +1,97 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseQuery(t *testing.T) {
+	userID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		input    string
+		want     Query
+		wantText string
+		wantErr  bool
+	}{
+		{
+			name:     "Plain words",
+			input:    "hello   world",
+			want:     Query{Terms: []string{"hello", "world"}},
+			wantText: "hello world",
+		},
+		{
+			name:     "Quoted phrase",
+			input:    `go "  gopher   party " now`,
+			want:     Query{Terms: []string{"go", "now"}, Phrases: []string{"gopher party"}},
+			wantText: `go now "gopher party"`,
+		},
+		{
+			name:     "Unterminated quote",
+			input:    `"open ended`,
+			want:     Query{Phrases: []string{"open ended"}},
+			wantText: `"open ended"`,
+		},
+		{
+			name:     "From handle",
+			input:    "from:@Chirper news",
+			want:     Query{Terms: []string{"news"}, FromHandle: "chirper"},
+			wantText: "news",
+		},
+		{
+			name:  "From user ID",
+			input: "from:" + userID.String(),
+			want:  Query{FromID: uuid.NullUUID{UUID: userID, Valid: true}},
+		},
+		{
+			name:     "Dates and tags",
+			input:    "since:2025-01-01 until:2025-02-01 #GoLang release",
+			want:     Query{Terms: []string{"release"}, Tags: []string{"golang"}, Since: &since, Until: &until},
+			wantText: "release",
+		},
+		{
+			name:  "Repeated tag",
+			input: "#go #Go #gopher",
+			want:  Query{Tags: []string{"go", "gopher"}},
+		},
+		{
+			name:    "Invalid date",
+			input:   "since:yesterday",
+			wantErr: true,
+		},
+		{
+			name:    "Empty from",
+			input:   "from: hello",
+			wantErr: true,
+		},
+		{
+			name:    "Empty query",
+			input:   `  "" `,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery() = %+v, want %+v", got, tt.want)
+			}
+			if text := got.Text(); text != tt.wantText {
+				t.Errorf("Text() = %q, want %q", text, tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+// Package search finds chirps matching a Query. Postgres serves it in
+// production; MemoryIndex gives tests the same behaviour without a
+// database.
+package search
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Highlight markers wrapped around matched words in a snippet.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// Result is one matching chirp. Snippet is its body as HTML: escaped,
+// with the matched words highlighted.
+type Result struct {
+	ChirpID uuid.UUID
+	Rank    float64
+	Snippet string
+}
+
+// Searcher runs a query and returns a page of results, best match first
+// and newest first among equals.
+type Searcher interface {
+	Search(ctx context.Context, q Query, limit, offset int) ([]Result, error)
+}
@@ -0,0 +1,224 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+// softAuthenticator is an in-memory WebAuthn authenticator, playing both
+// the browser's and the security key's part of a ceremony.
+type softAuthenticator struct {
+	t      *testing.T
+	rpID   string
+	origin string
+	alg    int
+	signer crypto.Signer
+
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+	// counterStep is added to signCount for every signature; zero mimics
+	// a synced passkey with no counter.
+	counterStep uint32
+	flags       byte
+}
+
+func newSoftAuthenticator(t *testing.T, rpID, origin string, alg int) *softAuthenticator {
+	t.Helper()
+	var signer crypto.Signer
+	var err error
+	switch alg {
+	case AlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	case AlgRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+	return &softAuthenticator{
+		t:            t,
+		rpID:         rpID,
+		origin:       origin,
+		alg:          alg,
+		signer:       signer,
+		credentialID: credentialID,
+		counterStep:  1,
+		flags:        flagUserPresent | flagUserVerified,
+	}
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	switch key := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		return encodeCBOR(cborMap{
+			{int64(coseKeyType), int64(coseKTYEC2)},
+			{int64(coseAlg), int64(AlgES256)},
+			{int64(coseCurve), int64(coseCrvP256)},
+			{int64(coseX), key.X.FillBytes(make([]byte, 32))},
+			{int64(coseY), key.Y.FillBytes(make([]byte, 32))},
+		})
+	case ed25519.PublicKey:
+		return encodeCBOR(cborMap{
+			{int64(coseKeyType), int64(coseKTYOKP)},
+			{int64(coseAlg), int64(AlgEdDSA)},
+			{int64(coseCurve), int64(coseCrvEd25519)},
+			{int64(coseX), []byte(key)},
+		})
+	case *rsa.PublicKey:
+		return encodeCBOR(cborMap{
+			{int64(coseKeyType), int64(coseKTYRSA)},
+			{int64(coseAlg), int64(AlgRS256)},
+			{int64(coseCurve), key.N.Bytes()},
+			{int64(coseX), big.NewInt(int64(key.E)).Bytes()},
+		})
+	}
+	a.t.Fatalf("unexpected key %T", a.signer.Public())
+	return nil
+}
+
+func (a *softAuthenticator) clientData(ceremony string, challenge []byte) []byte {
+	data, _ := json.Marshal(ClientData{
+		Type:      ceremony,
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Origin:    a.origin,
+	})
+	return data
+}
+
+func (a *softAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	a.signCount += a.counterStep
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+// create answers navigator.credentials.create with the given options.
+func (a *softAuthenticator) create(options CreationOptions) CreationResponse {
+	a.userHandle = options.User.ID
+
+	attested := make([]byte, 16) // an all-zero AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, a.coseKey()...)
+
+	resp := CreationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = a.clientData("webauthn.create", options.Challenge)
+	resp.Response.AttestationObject = encodeCBOR(cborMap{
+		{"fmt", "none"},
+		{"attStmt", cborMap{}},
+		{"authData", a.authenticatorData(a.flags|flagAttestedData, attested)},
+	})
+	return resp
+}
+
+// get answers navigator.credentials.get with the given options.
+func (a *softAuthenticator) get(options RequestOptions) AssertionResponse {
+	resp := AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = a.clientData("webauthn.get", options.Challenge)
+	resp.Response.AuthenticatorData = a.authenticatorData(a.flags, nil)
+	resp.Response.UserHandle = a.userHandle
+
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), resp.Response.AuthenticatorData...), clientDataHash[:]...)
+	resp.Response.Signature = a.sign(signed)
+	return resp
+}
+
+func (a *softAuthenticator) sign(data []byte) []byte {
+	var sig []byte
+	var err error
+	switch a.alg {
+	case AlgEdDSA:
+		sig, err = a.signer.Sign(rand.Reader, data, crypto.Hash(0))
+	default:
+		digest := sha256.Sum256(data)
+		sig, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return sig
+}
+
+// cborMap is an ordered CBOR map, for encoding.
+type cborMap []struct {
+	key   any
+	value any
+}
+
+// encodeCBOR encodes the subset of CBOR the authenticator produces.
+func encodeCBOR(v any) []byte {
+	header := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n <= 0xff:
+			return []byte{major<<5 | 24, byte(n)}
+		case n <= 0xffff:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		case n <= 0xffffffff:
+			return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+		default:
+			return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+		}
+	}
+
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return header(1, uint64(-1-v))
+		}
+		return header(0, uint64(v))
+	case []byte:
+		return append(header(2, uint64(len(v))), v...)
+	case string:
+		return append(header(3, uint64(len(v))), v...)
+	case []any:
+		out := header(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case cborMap:
+		out := header(5, uint64(len(v)))
+		for _, entry := range v {
+			out = append(out, encodeCBOR(entry.key)...)
+			out = append(out, encodeCBOR(entry.value)...)
+		}
+		return out
+	case bool:
+		if v {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case nil:
+		return []byte{0xf6}
+	}
+	panic("encodeCBOR: unsupported type")
+}
@@ -0,0 +1,59 @@
+package webauthn
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// A signed challenge is random bytes, an expiry in Unix seconds and an
+// HMAC over both.
+const (
+	signedChallengeRandom = 16
+	signedChallengeSize   = signedChallengeRandom + 8 + sha256.Size
+)
+
+var (
+	ErrInvalidChallenge = errors.New("challenge wasn't issued by this server")
+	ErrChallengeExpired = errors.New("challenge has expired")
+)
+
+// NewSignedChallenge returns a challenge that carries its own expiry and
+// is signed with key, so a server can hand it out without storing it.
+// Only its use needs recording, to stop it being answered twice.
+func NewSignedChallenge(key []byte, expires time.Time) ([]byte, error) {
+	challenge := make([]byte, signedChallengeRandom, signedChallengeSize)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, err
+	}
+	challenge = binary.BigEndian.AppendUint64(challenge, uint64(expires.Unix()))
+	return append(challenge, signChallenge(key, challenge)...), nil
+}
+
+// VerifySignedChallenge checks that challenge came from NewSignedChallenge
+// with the same key and hasn't expired.
+func VerifySignedChallenge(key, challenge []byte, now time.Time) error {
+	if len(challenge) != signedChallengeSize {
+		return ErrInvalidChallenge
+	}
+	body := challenge[:signedChallengeRandom+8]
+	if !hmac.Equal(challenge[len(body):], signChallenge(key, body)) {
+		return ErrInvalidChallenge
+	}
+	expires := time.Unix(int64(binary.BigEndian.Uint64(body[signedChallengeRandom:])), 0)
+	if !now.Before(expires) {
+		return ErrChallengeExpired
+	}
+	return nil
+}
+
+func signChallenge(key, body []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("chirpy-webauthn-challenge\x00"))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
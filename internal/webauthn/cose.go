@@ -0,0 +1,121 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers for the signatures Chirpy accepts.
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// COSE key parameters, from RFC 9053.
+const (
+	coseKeyType    = 1
+	coseAlg        = 3
+	coseCurve      = -1 // also the RSA modulus
+	coseX          = -2 // also the RSA exponent
+	coseY          = -3
+	coseKTYOKP     = 1
+	coseKTYEC2     = 2
+	coseKTYRSA     = 3
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+const minRSABits = 2048
+
+// publicKey is a credential's COSE key, ready to check signatures with.
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+func parseCOSEKey(raw []byte) (publicKey, error) {
+	decoded, rest, err := decodeCBOR(raw)
+	if err != nil {
+		return publicKey{}, err
+	}
+	if len(rest) != 0 {
+		return publicKey{}, errors.New("trailing data after public key")
+	}
+	params, ok := decoded.(map[any]any)
+	if !ok {
+		return publicKey{}, errors.New("public key isn't a COSE key")
+	}
+
+	kty, _ := params[int64(coseKeyType)].(int64)
+	alg, _ := params[int64(coseAlg)].(int64)
+	switch {
+	case alg == AlgES256 && kty == coseKTYEC2:
+		crv, _ := params[int64(coseCurve)].(int64)
+		x, _ := params[int64(coseX)].([]byte)
+		y, _ := params[int64(coseY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return publicKey{}, errors.New("invalid P-256 key")
+		}
+		// ecdh checks the point is on the curve.
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return publicKey{}, fmt.Errorf("invalid P-256 key: %w", err)
+		}
+		return publicKey{alg: alg, key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+	case alg == AlgEdDSA && kty == coseKTYOKP:
+		crv, _ := params[int64(coseCurve)].(int64)
+		x, _ := params[int64(coseX)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, errors.New("invalid Ed25519 key")
+		}
+		return publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case alg == AlgRS256 && kty == coseKTYRSA:
+		n, _ := params[int64(coseCurve)].([]byte)
+		e, _ := params[int64(coseX)].([]byte)
+		modulus := new(big.Int).SetBytes(n)
+		if modulus.BitLen() < minRSABits || len(e) == 0 || len(e) > 4 {
+			return publicKey{}, errors.New("invalid RSA key")
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return publicKey{alg: alg, key: &rsa.PublicKey{N: modulus, E: exponent}}, nil
+	default:
+		return publicKey{}, fmt.Errorf("unsupported key type %d with algorithm %d", kty, alg)
+	}
+}
+
+func (k publicKey) verify(data, sig []byte) error {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig)
+	default:
+		return fmt.Errorf("unsupported key %T", k.key)
+	}
+}
@@ -0,0 +1,448 @@
+// Package webauthn runs the server side of WebAuthn registration and
+// authentication, for signing in with passkeys. It supports the "none"
+// attestation format with ES256, EdDSA and RS256 keys, and always requires
+// user verification, since a passkey stands in for the password.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Timeout is how long a ceremony may take, as passed to the browser.
+const Timeout = 5 * time.Minute
+
+const challengeSize = 32
+
+// Authenticator data flags.
+const (
+	flagUserPresent       = 0x01
+	flagUserVerified      = 0x04
+	flagBackupEligible    = 0x08
+	flagBackupState       = 0x10
+	flagAttestedData      = 0x40
+	flagExtensionIncluded = 0x80
+)
+
+// ErrSignCount means an authenticator's signature counter went backwards,
+// which suggests the credential has been cloned.
+var ErrSignCount = errors.New("webauthn: signature counter went backwards")
+
+// Base64URL is binary data that travels in JSON as unpadded base64url,
+// as the WebAuthn JSON encodings expect. Padded input is accepted too.
+type Base64URL []byte
+
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+func (b Base64URL) String() string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// RelyingParty is the site credentials are scoped to. ID is its domain,
+// such as "example.com", and Origins are the exact origins, such as
+// "https://example.com", that ceremonies may come from.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// User is the account a credential is being created for. ID is an opaque
+// handle that the authenticator hands back at login; it mustn't contain
+// personal information.
+type User struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+// Credential is a verified public key credential, to be stored against the
+// user who registered it.
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte
+	SignCount      uint32
+	AAGUID         []byte
+	BackupEligible bool
+	BackupState    bool
+}
+
+// NewChallenge returns a random challenge for a single ceremony.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeSize)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+type rpEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type userEntity struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string    `json:"type"`
+	ID   Base64URL `json:"id"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions is the JSON form of PublicKeyCredentialCreationOptions,
+// which browsers turn into a navigator.credentials.create call with
+// PublicKeyCredential.parseCreationOptionsFromJSON.
+type CreationOptions struct {
+	RP                     rpEntity               `json:"rp"`
+	User                   userEntity             `json:"user"`
+	Challenge              Base64URL              `json:"challenge"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions is the JSON form of PublicKeyCredentialRequestOptions,
+// for navigator.credentials.get.
+type RequestOptions struct {
+	Challenge        Base64URL              `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, len(ids))
+	for i, id := range ids {
+		list[i] = CredentialDescriptor{Type: "public-key", ID: id}
+	}
+	return list
+}
+
+// CreationOptions asks for a discoverable credential for user, so it can
+// later sign in without typing anything. exclude lists the credentials the
+// user already has, so the same authenticator isn't registered twice.
+func (rp RelyingParty) CreationOptions(user User, challenge []byte, exclude [][]byte) CreationOptions {
+	return CreationOptions{
+		RP: rpEntity{ID: rp.ID, Name: rp.Name},
+		User: userEntity{
+			ID:          user.ID,
+			Name:        user.Name,
+			DisplayName: user.DisplayName,
+		},
+		Challenge: challenge,
+		PubKeyCredParams: []credentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions starts a login. With no allowed credentials, the
+// authenticator offers whichever discoverable credentials it has for the
+// site.
+func (rp RelyingParty) RequestOptions(challenge []byte, allow [][]byte) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          Timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: "required",
+	}
+}
+
+// CreationResponse is the JSON form of the PublicKeyCredential returned by
+// navigator.credentials.create.
+type CreationResponse struct {
+	ID       string    `json:"id"`
+	RawID    Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AttestationObject Base64URL `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of the PublicKeyCredential returned
+// by navigator.credentials.get.
+type AssertionResponse struct {
+	ID       string    `json:"id"`
+	RawID    Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AuthenticatorData Base64URL `json:"authenticatorData"`
+		Signature         Base64URL `json:"signature"`
+		UserHandle        Base64URL `json:"userHandle"`
+	} `json:"response"`
+}
+
+// ClientData is what the browser says about the ceremony it ran.
+// Challenge is base64url encoded.
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// ParseClientData decodes clientDataJSON without checking it, so the
+// caller can find the challenge it was issued for.
+func ParseClientData(clientDataJSON []byte) (ClientData, error) {
+	clientData := ClientData{}
+	err := json.Unmarshal(clientDataJSON, &clientData)
+	if err != nil {
+		return ClientData{}, fmt.Errorf("webauthn: invalid client data: %w", err)
+	}
+	return clientData, nil
+}
+
+func (rp RelyingParty) verifyClientData(clientDataJSON []byte, ceremony string, challenge []byte) error {
+	clientData, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return err
+	}
+	if clientData.Type != ceremony {
+		return fmt.Errorf("webauthn: client data is for %q, not %q", clientData.Type, ceremony)
+	}
+	if clientData.Challenge != base64.RawURLEncoding.EncodeToString(challenge) {
+		return errors.New("webauthn: challenge doesn't match")
+	}
+	if !slices.Contains(rp.Origins, clientData.Origin) {
+		return fmt.Errorf("webauthn: unexpected origin %q", clientData.Origin)
+	}
+	if clientData.CrossOrigin {
+		return errors.New("webauthn: cross-origin ceremonies aren't allowed")
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	// Only present during registration.
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+func parseAuthenticatorData(data []byte) (authenticatorData, error) {
+	if len(data) < 37 {
+		return authenticatorData{}, errors.New("webauthn: authenticator data too short")
+	}
+	authData := authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.flags&flagAttestedData != 0 {
+		if len(rest) < 18 {
+			return authenticatorData{}, errors.New("webauthn: attested credential data too short")
+		}
+		authData.aaguid = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength > 1023 || len(rest) < idLength {
+			return authenticatorData{}, errors.New("webauthn: invalid credential ID")
+		}
+		authData.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, fmt.Errorf("webauthn: invalid public key: %w", err)
+		}
+		authData.publicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if authData.flags&flagExtensionIncluded != 0 {
+		extensions, after, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, fmt.Errorf("webauthn: invalid extensions: %w", err)
+		}
+		if _, ok := extensions.(map[any]any); !ok {
+			return authenticatorData{}, errors.New("webauthn: extensions aren't a map")
+		}
+		rest = after
+	}
+
+	if len(rest) != 0 {
+		return authenticatorData{}, errors.New("webauthn: trailing data after authenticator data")
+	}
+	return authData, nil
+}
+
+func (rp RelyingParty) checkAuthenticatorData(authData authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.rpIDHash, rpIDHash[:]) {
+		return errors.New("webauthn: credential is for another site")
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return errors.New("webauthn: user wasn't present")
+	}
+	if authData.flags&flagUserVerified == 0 {
+		return errors.New("webauthn: user wasn't verified")
+	}
+	return nil
+}
+
+// VerifyRegistration checks a navigator.credentials.create response
+// against the challenge issued for it and returns the new credential.
+func (rp RelyingParty) VerifyRegistration(challenge []byte, resp CreationResponse) (Credential, error) {
+	if resp.Type != "public-key" || resp.ID != resp.RawID.String() {
+		return Credential{}, errors.New("webauthn: malformed credential")
+	}
+	err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	decoded, rest, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: invalid attestation object: %w", err)
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok || len(rest) != 0 {
+		return Credential{}, errors.New("webauthn: invalid attestation object")
+	}
+	// Options ask for no attestation, which browsers enforce by replacing
+	// whatever the authenticator produced with "none".
+	if format, _ := attestation["fmt"].(string); format != "none" {
+		return Credential{}, fmt.Errorf("webauthn: unsupported attestation format %q", format)
+	}
+	if statement, ok := attestation["attStmt"].(map[any]any); !ok || len(statement) != 0 {
+		return Credential{}, errors.New("webauthn: unexpected attestation statement")
+	}
+	rawAuthData, _ := attestation["authData"].([]byte)
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	err = rp.checkAuthenticatorData(authData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if authData.credentialID == nil {
+		return Credential{}, errors.New("webauthn: no credential in registration")
+	}
+	if !bytes.Equal(authData.credentialID, resp.RawID) {
+		return Credential{}, errors.New("webauthn: credential ID doesn't match")
+	}
+	_, err = parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: %w", err)
+	}
+
+	return Credential{
+		ID:             authData.credentialID,
+		PublicKey:      authData.publicKey,
+		SignCount:      authData.signCount,
+		AAGUID:         authData.aaguid,
+		BackupEligible: authData.flags&flagBackupEligible != 0,
+		BackupState:    authData.flags&flagBackupState != 0,
+	}, nil
+}
+
+// VerifyAssertion checks a navigator.credentials.get response made with
+// cred against the challenge issued for it. It returns cred updated with
+// the new signature counter and backup state, for the caller to store.
+// Checking that the credential belongs to the right user, including the
+// response's user handle, is left to the caller.
+func (rp RelyingParty) VerifyAssertion(challenge []byte, cred Credential, resp AssertionResponse) (Credential, error) {
+	if resp.Type != "public-key" || resp.ID != resp.RawID.String() {
+		return Credential{}, errors.New("webauthn: malformed credential")
+	}
+	if !bytes.Equal(resp.RawID, cred.ID) {
+		return Credential{}, errors.New("webauthn: credential ID doesn't match")
+	}
+	err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	authData, err := parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return Credential{}, err
+	}
+	err = rp.checkAuthenticatorData(authData)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	key, err := parseCOSEKey(cred.PublicKey)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: %w", err)
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), resp.Response.AuthenticatorData...), clientDataHash[:]...)
+	err = key.verify(signed, resp.Response.Signature)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: %w", err)
+	}
+
+	// Authenticators that don't keep a counter, such as synced passkeys,
+	// always report zero.
+	if (authData.signCount != 0 || cred.SignCount != 0) && authData.signCount <= cred.SignCount {
+		return Credential{}, ErrSignCount
+	}
+
+	cred.SignCount = authData.signCount
+	cred.BackupState = authData.flags&flagBackupState != 0
+	return cred, nil
+}
@@ -0,0 +1,332 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testRP = RelyingParty{
+	ID:      "localhost",
+	Name:    "Chirpy",
+	Origins: []string{"http://localhost:8080"},
+}
+
+var testUser = User{
+	ID:          []byte("0123456789abcdef"),
+	Name:        "user@example.com",
+	DisplayName: "Test User",
+}
+
+// register runs a registration ceremony with a and returns the stored
+// credential.
+func register(t *testing.T, a *softAuthenticator) Credential {
+	t.Helper()
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := a.create(testRP.CreationOptions(testUser, challenge, nil))
+	cred, err := testRP.VerifyRegistration(challenge, roundTrip(t, resp))
+	if err != nil {
+		t.Fatalf("VerifyRegistration() error = %v", err)
+	}
+	return cred
+}
+
+// roundTrip sends v through JSON, as it would travel between browser and
+// server.
+func roundTrip[T any](t *testing.T, v T) T {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out T
+	err = json.Unmarshal(data, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestCeremonies(t *testing.T) {
+	tests := []struct {
+		name string
+		alg  int
+	}{
+		{name: "ES256", alg: AlgES256},
+		{name: "EdDSA", alg: AlgEdDSA},
+		{name: "RS256", alg: AlgRS256},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newSoftAuthenticator(t, testRP.ID, testRP.Origins[0], tt.alg)
+			cred := register(t, a)
+			if !bytes.Equal(cred.ID, a.credentialID) {
+				t.Errorf("credential ID = %x, want %x", cred.ID, a.credentialID)
+			}
+			if cred.SignCount != 1 {
+				t.Errorf("SignCount = %d, want 1", cred.SignCount)
+			}
+
+			for i := 0; i < 2; i++ {
+				challenge, _ := NewChallenge()
+				resp := a.get(testRP.RequestOptions(challenge, nil))
+				cred, err := testRP.VerifyAssertion(challenge, cred, roundTrip(t, resp))
+				if err != nil {
+					t.Fatalf("VerifyAssertion() error = %v", err)
+				}
+				if cred.SignCount != a.signCount {
+					t.Errorf("SignCount = %d, want %d", cred.SignCount, a.signCount)
+				}
+				if !bytes.Equal(resp.Response.UserHandle, testUser.ID) {
+					t.Errorf("UserHandle = %q, want %q", resp.Response.UserHandle, testUser.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(a *softAuthenticator, challenge []byte) ([]byte, CreationResponse)
+	}{
+		{
+			name: "Wrong challenge",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, CreationResponse) {
+				other, _ := NewChallenge()
+				return challenge, a.create(testRP.CreationOptions(testUser, other, nil))
+			},
+		},
+		{
+			name: "Wrong origin",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, CreationResponse) {
+				a.origin = "https://evil.example"
+				return challenge, a.create(testRP.CreationOptions(testUser, challenge, nil))
+			},
+		},
+		{
+			name: "Wrong RP ID",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, CreationResponse) {
+				a.rpID = "evil.example"
+				return challenge, a.create(testRP.CreationOptions(testUser, challenge, nil))
+			},
+		},
+		{
+			name: "User not verified",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, CreationResponse) {
+				a.flags = flagUserPresent
+				return challenge, a.create(testRP.CreationOptions(testUser, challenge, nil))
+			},
+		},
+		{
+			name: "Assertion instead of registration",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, CreationResponse) {
+				resp := a.create(testRP.CreationOptions(testUser, challenge, nil))
+				resp.Response.ClientDataJSON = a.clientData("webauthn.get", challenge)
+				return challenge, resp
+			},
+		},
+		{
+			name: "Mismatched credential ID",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, CreationResponse) {
+				resp := a.create(testRP.CreationOptions(testUser, challenge, nil))
+				resp.RawID = []byte("another credential")
+				resp.ID = resp.RawID.String()
+				return challenge, resp
+			},
+		},
+		{
+			name: "Truncated attestation object",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, CreationResponse) {
+				resp := a.create(testRP.CreationOptions(testUser, challenge, nil))
+				resp.Response.AttestationObject = resp.Response.AttestationObject[:40]
+				return challenge, resp
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newSoftAuthenticator(t, testRP.ID, testRP.Origins[0], AlgES256)
+			challenge, _ := NewChallenge()
+			expected, resp := tt.tamper(a, challenge)
+			_, err := testRP.VerifyRegistration(expected, resp)
+			if err == nil {
+				t.Error("VerifyRegistration() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(a *softAuthenticator, challenge []byte) ([]byte, AssertionResponse)
+		wantErr error
+	}{
+		{
+			name: "Wrong challenge",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, AssertionResponse) {
+				other, _ := NewChallenge()
+				return challenge, a.get(testRP.RequestOptions(other, nil))
+			},
+		},
+		{
+			name: "Tampered signature",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, AssertionResponse) {
+				resp := a.get(testRP.RequestOptions(challenge, nil))
+				resp.Response.Signature[len(resp.Response.Signature)-1] ^= 0xff
+				return challenge, resp
+			},
+		},
+		{
+			name: "Tampered client data",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, AssertionResponse) {
+				resp := a.get(testRP.RequestOptions(challenge, nil))
+				resp.Response.ClientDataJSON = append(resp.Response.ClientDataJSON[:len(resp.Response.ClientDataJSON)-1], []byte(`,"extra":1}`)...)
+				return challenge, resp
+			},
+		},
+		{
+			name: "Wrong origin",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, AssertionResponse) {
+				a.origin = "https://evil.example"
+				return challenge, a.get(testRP.RequestOptions(challenge, nil))
+			},
+		},
+		{
+			name: "User not verified",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, AssertionResponse) {
+				a.flags = flagUserPresent
+				return challenge, a.get(testRP.RequestOptions(challenge, nil))
+			},
+		},
+		{
+			name: "Counter went backwards",
+			tamper: func(a *softAuthenticator, challenge []byte) ([]byte, AssertionResponse) {
+				a.signCount = 0
+				a.counterStep = 0
+				return challenge, a.get(testRP.RequestOptions(challenge, nil))
+			},
+			wantErr: ErrSignCount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newSoftAuthenticator(t, testRP.ID, testRP.Origins[0], AlgES256)
+			cred := register(t, a)
+			challenge, _ := NewChallenge()
+			expected, resp := tt.tamper(a, challenge)
+			_, err := testRP.VerifyAssertion(expected, cred, resp)
+			if err == nil {
+				t.Fatal("VerifyAssertion() error = nil, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyAssertion() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionWithoutCounter(t *testing.T) {
+	// Synced passkeys report a counter of zero every time.
+	a := newSoftAuthenticator(t, testRP.ID, testRP.Origins[0], AlgES256)
+	a.counterStep = 0
+	cred := register(t, a)
+
+	for i := 0; i < 2; i++ {
+		challenge, _ := NewChallenge()
+		_, err := testRP.VerifyAssertion(challenge, cred, a.get(testRP.RequestOptions(challenge, nil)))
+		if err != nil {
+			t.Fatalf("VerifyAssertion() error = %v", err)
+		}
+	}
+}
+
+func TestVerifySignedChallenge(t *testing.T) {
+	key := []byte("server secret")
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	challenge, err := NewSignedChallenge(key, now.Add(Timeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Clone(challenge)
+	tampered[0] ^= 0xff
+
+	tests := []struct {
+		name      string
+		key       []byte
+		challenge []byte
+		now       time.Time
+		wantErr   error
+	}{
+		{name: "Valid", key: key, challenge: challenge, now: now},
+		{name: "Expired", key: key, challenge: challenge, now: now.Add(Timeout), wantErr: ErrChallengeExpired},
+		{name: "Other key", key: []byte("other secret"), challenge: challenge, now: now, wantErr: ErrInvalidChallenge},
+		{name: "Tampered", key: key, challenge: tampered, now: now, wantErr: ErrInvalidChallenge},
+		{name: "Truncated", key: key, challenge: challenge[:len(challenge)-1], now: now, wantErr: ErrInvalidChallenge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignedChallenge(tt.key, tt.challenge, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifySignedChallenge() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    any
+		wantErr bool
+	}{
+		{name: "Small int", data: []byte{0x0a}, want: int64(10)},
+		{name: "Negative int", data: []byte{0x26}, want: int64(-7)},
+		{name: "Two-byte negative int", data: []byte{0x39, 0x01, 0x00}, want: int64(-257)},
+		{name: "Byte string", data: []byte{0x42, 0x01, 0x02}, want: []byte{1, 2}},
+		{name: "Text string", data: []byte{0x63, 'f', 'm', 't'}, want: "fmt"},
+		{name: "Array", data: []byte{0x82, 0x01, 0xf5}, want: []any{int64(1), true}},
+		{name: "Map", data: []byte{0xa1, 0x01, 0x02}, want: map[any]any{int64(1): int64(2)}},
+		{name: "Truncated string", data: []byte{0x45, 0x01}, wantErr: true},
+		{name: "Huge array length", data: []byte{0x9a, 0xff, 0xff, 0xff, 0xff}, wantErr: true},
+		{name: "Duplicate map key", data: []byte{0xa2, 0x01, 0x02, 0x01, 0x03}, wantErr: true},
+		{name: "Indefinite length", data: []byte{0x9f, 0xff}, wantErr: true},
+		{name: "Tag", data: []byte{0xc0, 0x01}, wantErr: true},
+		{name: "Float", data: []byte{0xf9, 0x00, 0x00}, wantErr: true},
+		{name: "Invalid UTF-8", data: []byte{0x61, 0xff}, wantErr: true},
+		{name: "Empty", data: []byte{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := decodeCBOR(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCBOR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCBOR() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORDepth(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+	nested = append(nested, 0x01)
+	_, _, err := decodeCBOR(nested)
+	if err == nil {
+		t.Error("decodeCBOR() error = nil for deeply nested input")
+	}
+}
@@ -0,0 +1,287 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455 section 1.3.
+	got := acceptKey("dGhlIHNhbXBsZSBub25jZQ==")
+	want := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+	if got != want {
+		t.Errorf("acceptKey() = %q, want %q", got, want)
+	}
+}
+
+func TestUpgradeRejectsBadHandshake(t *testing.T) {
+	valid := func() *http.Request {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		r.Header.Set("Connection", "keep-alive, Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *http.Request)
+	}{
+		{
+			name:   "Wrong method",
+			modify: func(r *http.Request) { r.Method = "POST" },
+		},
+		{
+			name:   "Missing upgrade",
+			modify: func(r *http.Request) { r.Header.Del("Upgrade") },
+		},
+		{
+			name:   "Connection without upgrade token",
+			modify: func(r *http.Request) { r.Header.Set("Connection", "keep-alive") },
+		},
+		{
+			name:   "Old version",
+			modify: func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") },
+		},
+		{
+			name:   "Malformed key",
+			modify: func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "short") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(r)
+			_, err := Upgrade(httptest.NewRecorder(), r)
+			if !errors.Is(err, ErrBadHandshake) {
+				t.Errorf("Upgrade() error = %v, want %v", err, ErrBadHandshake)
+			}
+		})
+	}
+}
+
+// testClient speaks just enough of the client side of the protocol to
+// exercise the server.
+type testClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dial(t *testing.T, url string) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	err = req.Write(conn)
+	if err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	if resp.StatusCode != 101 || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake response = %d %v", resp.StatusCode, resp.Header)
+	}
+	return &testClient{conn: conn, br: br}
+}
+
+func (c *testClient) send(t *testing.T, fin bool, opcode int, payload []byte, masked bool) {
+	t.Helper()
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, maskBit|byte(len(payload)))
+	default:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	body := append([]byte(nil), payload...)
+	if masked {
+		mask := []byte{1, 2, 3, 4}
+		frame = append(frame, mask...)
+		for i := range body {
+			body[i] ^= mask[i%4]
+		}
+	}
+	frame = append(frame, body...)
+	_, err := c.conn.Write(frame)
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+}
+
+func (c *testClient) receive(t *testing.T) (opcode int, payload []byte) {
+	t.Helper()
+	var header [2]byte
+	_, err := io.ReadFull(c.br, header[:])
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload = make([]byte, length)
+	_, err = io.ReadFull(c.br, payload)
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	return int(header[0] & 0x0f), payload
+}
+
+func echoServer(t *testing.T, readLimit int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		defer conn.Close()
+		conn.SetReadLimit(readLimit)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, data)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestConnEcho(t *testing.T) {
+	server := echoServer(t, 1024)
+	client := dial(t, server.URL)
+
+	client.send(t, true, TextMessage, []byte("hello"), true)
+	opcode, payload := client.receive(t)
+	if opcode != TextMessage || string(payload) != "hello" {
+		t.Errorf("echo = %d %q, want %d %q", opcode, payload, TextMessage, "hello")
+	}
+
+	long := strings.Repeat("x", 300)
+	client.send(t, true, BinaryMessage, []byte(long), true)
+	opcode, payload = client.receive(t)
+	if opcode != BinaryMessage || string(payload) != long {
+		t.Errorf("echo of %d bytes = %d, %d bytes", len(long), opcode, len(payload))
+	}
+}
+
+func TestConnFragmentsAndPing(t *testing.T) {
+	server := echoServer(t, 1024)
+	client := dial(t, server.URL)
+
+	client.send(t, false, TextMessage, []byte("hel"), true)
+	client.send(t, true, PingMessage, []byte("ping"), true)
+	client.send(t, true, continuationFrame, []byte("lo"), true)
+
+	opcode, payload := client.receive(t)
+	if opcode != PongMessage || string(payload) != "ping" {
+		t.Errorf("first frame = %d %q, want pong %q", opcode, payload, "ping")
+	}
+	opcode, payload = client.receive(t)
+	if opcode != TextMessage || string(payload) != "hello" {
+		t.Errorf("second frame = %d %q, want text %q", opcode, payload, "hello")
+	}
+}
+
+func TestConnClose(t *testing.T) {
+	tests := []struct {
+		name     string
+		fin      bool
+		opcode   int
+		payload  []byte
+		masked   bool
+		wantCode int
+	}{
+		{
+			name:     "Client closes",
+			fin:      true,
+			opcode:   CloseMessage,
+			payload:  []byte{0x03, 0xe8},
+			masked:   true,
+			wantCode: CloseNormalClosure,
+		},
+		{
+			name:     "Unmasked frame",
+			fin:      true,
+			opcode:   TextMessage,
+			payload:  []byte("hi"),
+			masked:   false,
+			wantCode: CloseProtocolError,
+		},
+		{
+			name:     "Message too big",
+			fin:      true,
+			opcode:   TextMessage,
+			payload:  []byte(strings.Repeat("x", 200)),
+			masked:   true,
+			wantCode: CloseMessageTooBig,
+		},
+		{
+			name:     "Invalid UTF-8",
+			fin:      true,
+			opcode:   TextMessage,
+			payload:  []byte{0xff, 0xfe},
+			masked:   true,
+			wantCode: CloseInvalidPayload,
+		},
+		{
+			name:     "Fragmented control frame",
+			fin:      false,
+			opcode:   PingMessage,
+			payload:  nil,
+			masked:   true,
+			wantCode: CloseProtocolError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := echoServer(t, 100)
+			client := dial(t, server.URL)
+
+			client.send(t, tt.fin, tt.opcode, tt.payload, tt.masked)
+			opcode, payload := client.receive(t)
+			if opcode != CloseMessage || len(payload) < 2 {
+				t.Fatalf("got frame %d %q, want a close frame", opcode, payload)
+			}
+			if code := int(binary.BigEndian.Uint16(payload)); code != tt.wantCode {
+				t.Errorf("close code = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
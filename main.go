@@ -5,39 +5,113 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/mjossany/Chirpy/internal/blobstore"
 	"github.com/mjossany/Chirpy/internal/database"
+	"github.com/mjossany/Chirpy/internal/mail"
+	"github.com/mjossany/Chirpy/internal/pubsub"
+	"github.com/mjossany/Chirpy/internal/search"
+	"github.com/mjossany/Chirpy/internal/webauthn"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	db             *database.Queries
-	platform       string
-	jwtSecret      string
-	polkaKey       string
+	fileserverHits  atomic.Int32
+	db              *database.Queries
+	dbConn          *sql.DB
+	platform        string
+	jwtSecret       string
+	polkaKey        string
+	editWindow      time.Duration
+	editRequiresRed bool
+	events          *pubsub.Broker
+	searcher        search.Searcher
+	blobs           blobstore.BlobStore
+	mailer          mail.Mailer
+	relyingParty    webauthn.RelyingParty
 }
 
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID         `json:"id"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Email         string            `json:"email"`
+	EmailVerified bool              `json:"email_verified"`
+	PendingEmail  string            `json:"pending_email,omitempty"`
+	Handle        string            `json:"handle,omitempty"`
+	DisplayName   string            `json:"display_name"`
+	Bio           string            `json:"bio"`
+	Website       string            `json:"website"`
+	AvatarURLs    map[string]string `json:"avatar_urls,omitempty"`
+	BannerURLs    map[string]string `json:"banner_urls,omitempty"`
+	Token         string            `json:"token"`
+	RefreshToken  string            `json:"refresh_token"`
+	IsChirpyRed   bool              `json:"is_chirpy_red"`
+}
+
+func (cfg *apiConfig) userFromDatabase(dbUser database.User) User {
+	return User{
+		ID:            dbUser.ID,
+		CreatedAt:     dbUser.CreatedAt,
+		UpdatedAt:     dbUser.UpdatedAt,
+		Email:         dbUser.Email,
+		EmailVerified: dbUser.EmailVerifiedAt.Valid,
+		Handle:        dbUser.Handle.String,
+		DisplayName:   dbUser.DisplayName,
+		Bio:           dbUser.Bio,
+		Website:       dbUser.Website,
+		AvatarURLs:    avatarImage.urls(cfg, dbUser.AvatarKey),
+		BannerURLs:    bannerImage.urls(cfg, dbUser.BannerKey),
+		IsChirpyRed:   dbUser.IsChirpyRed,
+	}
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID           uuid.UUID     `json:"id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	Body         string        `json:"body"`
+	UserID       uuid.UUID     `json:"user_id"`
+	ParentID     uuid.NullUUID `json:"parent_id"`
+	RootID       uuid.NullUUID `json:"root_id"`
+	Deleted      bool          `json:"deleted"`
+	LikeCount    int32         `json:"like_count"`
+	LikedByMe    *bool         `json:"liked_by_me,omitempty"`
+	RechirpCount int32         `json:"rechirp_count"`
+	QuoteCount   int32         `json:"quote_count"`
+	EditedAt     *time.Time    `json:"edited_at"`
+	Mentions     []Mention     `json:"mentions"`
+	Media        []Attachment  `json:"media"`
+	Poll         *Poll         `json:"poll,omitempty"`
+	Pinned       bool          `json:"pinned,omitempty"`
+	Rechirped    *Chirp        `json:"rechirped_chirp,omitempty"`
+	Quoted       *QuotedChirp  `json:"quoted_chirp,omitempty"`
+
+	rechirpOf uuid.NullUUID
+	quoteOf   uuid.NullUUID
+	isQuote   bool
+}
+
+// Mention is an @handle in a chirp body that resolved to a user. Start and
+// End are offsets into the body in Unicode code points, End exclusive.
+type Mention struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+	Start  int32     `json:"start"`
+	End    int32     `json:"end"`
+}
+
+// QuotedChirp is the chirp a quote points at, or a placeholder once that
+// chirp has been deleted.
+type QuotedChirp struct {
+	*Chirp
+	Unavailable bool   `json:"unavailable,omitempty"`
+	Placeholder string `json:"placeholder,omitempty"`
 }
 
 type RefreshToken struct {
@@ -67,12 +141,99 @@ func main() {
 	jwtSecret := os.Getenv("JWT_SECRET")
 	polkaKey := os.Getenv("POLKA_KEY")
 
+	editWindow := time.Duration(0)
+	if window := os.Getenv("CHIRP_EDIT_WINDOW"); window != "" {
+		editWindow, err = time.ParseDuration(window)
+		if err != nil {
+			log.Fatalf("Invalid CHIRP_EDIT_WINDOW: %s", err)
+		}
+	}
+	editRequiresRed := os.Getenv("CHIRP_EDIT_RED_ONLY") == "true"
+
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "./uploads"
+	}
+	var blobs blobstore.BlobStore
+	serveUploads := false
+	switch store := os.Getenv("MEDIA_STORE"); store {
+	case "", "local":
+		blobs = blobstore.NewLocalStore(mediaDir, "/uploads")
+		serveUploads = true
+	case "s3":
+		blobs = blobstore.NewS3Store(blobstore.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		})
+	default:
+		log.Fatalf("Invalid MEDIA_STORE: %s", store)
+	}
+
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "Chirpy <no-reply@localhost>"
+	}
+	var mailer mail.Mailer
+	switch transport := os.Getenv("MAIL_TRANSPORT"); transport {
+	case "":
+		log.Fatal("MAIL_TRANSPORT must be set")
+	case "log":
+		// Logged mail never reaches anyone, which would quietly break
+		// verification and password resets anywhere but development.
+		if platform != "dev" {
+			log.Fatal("MAIL_TRANSPORT=log is only allowed when PLATFORM=dev")
+		}
+		mailer = mail.NewLogMailer(log.Default())
+	case "smtp":
+		mailer = mail.NewSMTPMailer(mail.SMTPConfig{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     mailFrom,
+		})
+	case "file":
+		mailDir := os.Getenv("MAIL_DIR")
+		if mailDir == "" {
+			mailDir = "./mail"
+		}
+		mailer = mail.NewFileMailer(mailDir, mailFrom)
+	default:
+		log.Fatalf("Invalid MAIL_TRANSPORT: %s", transport)
+	}
+
+	relyingParty := webauthn.RelyingParty{
+		ID:      os.Getenv("WEBAUTHN_RP_ID"),
+		Name:    os.Getenv("WEBAUTHN_RP_NAME"),
+		Origins: []string{"http://localhost:" + port},
+	}
+	if relyingParty.ID == "" {
+		relyingParty.ID = "localhost"
+	}
+	if relyingParty.Name == "" {
+		relyingParty.Name = "Chirpy"
+	}
+	if origins := os.Getenv("WEBAUTHN_ORIGINS"); origins != "" {
+		relyingParty.Origins = strings.Split(origins, ",")
+	}
+
 	apiCfg := &apiConfig{
-		fileserverHits: atomic.Int32{},
-		db:             dbQueries,
-		platform:       platform,
-		jwtSecret:      jwtSecret,
-		polkaKey:       polkaKey,
+		fileserverHits:  atomic.Int32{},
+		db:              dbQueries,
+		dbConn:          dbConn,
+		platform:        platform,
+		jwtSecret:       jwtSecret,
+		polkaKey:        polkaKey,
+		editWindow:      editWindow,
+		editRequiresRed: editRequiresRed,
+		events:          pubsub.NewBroker(streamHistorySize, streamBufferSize),
+		searcher:        search.NewPostgresSearcher(dbQueries),
+		blobs:           blobs,
+		mailer:          mailer,
+		relyingParty:    relyingParty,
 	}
 
 	serverMux := http.NewServeMux()
@@ -84,26 +245,98 @@ func main() {
 
 	serverMux.Handle("/app/", http.StripPrefix("/app", apiCfg.middlewareMetricsInc(http.FileServer(http.Dir(filepathRoot)))))
 
+	if serveUploads {
+		serverMux.Handle("GET /uploads/", http.StripPrefix("/uploads", noDirectoryListing(http.FileServer(http.Dir(mediaDir)))))
+	}
+
 	serverMux.HandleFunc("GET /api/healthz", handleHealthCheck)
 
 	serverMux.HandleFunc("POST /api/users", apiCfg.handleUserCreation)
-	serverMux.HandleFunc("PUT /api/users", apiCfg.handleUserUpdate)
+	serverMux.HandleFunc("PATCH /api/users", apiCfg.handleUserPatch)
+	serverMux.HandleFunc("POST /api/users/email/confirm", apiCfg.handleEmailChangeConfirm)
+	serverMux.HandleFunc("POST /api/users/verify", apiCfg.handleUserVerify)
+	serverMux.HandleFunc("POST /api/users/verify/resend", apiCfg.handleResendVerification)
+	serverMux.HandleFunc("GET /api/users/{handleOrID}", apiCfg.handleUserProfile)
+	serverMux.HandleFunc("PUT /api/users/avatar", apiCfg.handleAvatarUpload)
+	serverMux.HandleFunc("DELETE /api/users/avatar", apiCfg.handleAvatarDelete)
+	serverMux.HandleFunc("PUT /api/users/banner", apiCfg.handleBannerUpload)
+	serverMux.HandleFunc("DELETE /api/users/banner", apiCfg.handleBannerDelete)
+
+	serverMux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.handleFollow)
+	serverMux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.handleUnfollow)
+	serverMux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.handleFollowerList)
+	serverMux.HandleFunc("GET /api/users/{userID}/following", apiCfg.handleFollowingList)
+	serverMux.HandleFunc("GET /api/users/{userID}/chirps", apiCfg.handleUserChirps)
+	serverMux.HandleFunc("GET /api/users/{userID}/likes", apiCfg.handleUserLikes)
+	serverMux.HandleFunc("GET /api/users/{userID}/mentions", apiCfg.handleUserMentions)
 
 	serverMux.HandleFunc("POST /api/login", apiCfg.handleUserLogin)
+	serverMux.HandleFunc("POST /api/login/mfa", apiCfg.handleLoginMFA)
+
+	serverMux.HandleFunc("POST /api/webauthn/register/begin", apiCfg.handleWebAuthnRegisterBegin)
+	serverMux.HandleFunc("POST /api/webauthn/register/finish", apiCfg.handleWebAuthnRegisterFinish)
+	serverMux.HandleFunc("POST /api/webauthn/login/begin", apiCfg.handleWebAuthnLoginBegin)
+	serverMux.HandleFunc("POST /api/webauthn/login/finish", apiCfg.handleWebAuthnLoginFinish)
+	serverMux.HandleFunc("GET /api/webauthn/credentials", apiCfg.handlePasskeyList)
+	serverMux.HandleFunc("DELETE /api/webauthn/credentials/{credentialID}", apiCfg.handlePasskeyDelete)
+
+	serverMux.HandleFunc("GET /api/users/2fa", apiCfg.handleTwoFactorStatus)
+	serverMux.HandleFunc("POST /api/users/2fa/totp", apiCfg.handleTOTPEnroll)
+	serverMux.HandleFunc("POST /api/users/2fa/totp/confirm", apiCfg.handleTOTPConfirm)
+	serverMux.HandleFunc("POST /api/users/2fa/totp/disable", apiCfg.handleTOTPDisable)
+	serverMux.HandleFunc("POST /api/users/2fa/recovery_codes", apiCfg.handleRecoveryCodesRegenerate)
+
+	serverMux.HandleFunc("POST /api/password/forgot", apiCfg.handleForgotPassword)
+	serverMux.HandleFunc("POST /api/password/reset", apiCfg.handleResetPassword)
 
 	serverMux.HandleFunc("POST /api/refresh", apiCfg.handleTokenRefresh)
 	serverMux.HandleFunc("POST /api/revoke", apiCfg.handleTokenRevoke)
 
 	serverMux.HandleFunc("GET /api/chirps", apiCfg.handleChirpList)
 	serverMux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handleGetChirp)
+	serverMux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.handleChirpThread)
 	serverMux.HandleFunc("POST /api/chirps", apiCfg.handleChirpCreation)
+	serverMux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.handleChirpEdit)
+	serverMux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.handleChirpRevisions)
 	serverMux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handleDeleteChirp)
+	serverMux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.handleLikeChirp)
+	serverMux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.handleUnlikeChirp)
+	serverMux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.handleRechirp)
+	serverMux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.handleUndoRechirp)
+	serverMux.HandleFunc("POST /api/chirps/{chirpID}/poll/vote", apiCfg.handlePollVote)
+	serverMux.HandleFunc("POST /api/chirps/{chirpID}/pin", apiCfg.handlePinChirp)
+	serverMux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", apiCfg.handleUnpinChirp)
+	serverMux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", apiCfg.handleBookmarkChirp)
+	serverMux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.handleUnbookmarkChirp)
+
+	serverMux.HandleFunc("GET /api/bookmarks", apiCfg.handleBookmarkList)
+	serverMux.HandleFunc("GET /api/bookmarks/folders", apiCfg.handleBookmarkFolderList)
+	serverMux.HandleFunc("POST /api/bookmarks/folders", apiCfg.handleBookmarkFolderCreate)
+	serverMux.HandleFunc("PUT /api/bookmarks/folders/{folderID}", apiCfg.handleBookmarkFolderRename)
+	serverMux.HandleFunc("DELETE /api/bookmarks/folders/{folderID}", apiCfg.handleBookmarkFolderDelete)
+
+	serverMux.HandleFunc("POST /api/media", apiCfg.handleMediaUpload)
+
+	serverMux.HandleFunc("GET /api/timeline", apiCfg.handleTimeline)
+	serverMux.HandleFunc("GET /api/search/chirps", apiCfg.handleSearchChirps)
+
+	serverMux.HandleFunc("GET /api/stream", apiCfg.handleStream)
+	serverMux.HandleFunc("GET /api/ws", apiCfg.handleWebSocket)
+
+	serverMux.HandleFunc("GET /api/notifications", apiCfg.handleNotificationList)
+	serverMux.HandleFunc("GET /api/notifications/unread_count", apiCfg.handleNotificationUnreadCount)
+	serverMux.HandleFunc("POST /api/notifications/read", apiCfg.handleNotificationRead)
+
+	serverMux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.handleHashtagChirps)
+	serverMux.HandleFunc("GET /api/trending", apiCfg.handleTrending)
 
 	serverMux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlePolkaWebhook)
 
 	serverMux.HandleFunc("GET /admin/metrics", apiCfg.handleMetrics)
 	serverMux.HandleFunc("POST /admin/reset", apiCfg.handleReset)
 
+	go apiCfg.reapUnattachedMedia()
+
 	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
 	log.Fatal(srv.ListenAndServe())
 }
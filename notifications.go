@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mjossany/Chirpy/internal/database"
+)
+
+const (
+	notificationKindLike    = "like"
+	notificationKindReply   = "reply"
+	notificationKindMention = "mention"
+	notificationKindFollow  = "follow"
+	notificationKindRechirp = "rechirp"
+)
+
+// Notification is a single notification as pushed to /api/stream.
+type Notification struct {
+	ID        uuid.UUID     `json:"id"`
+	Kind      string        `json:"kind"`
+	ActorID   uuid.UUID     `json:"actor_id"`
+	ChirpID   uuid.NullUUID `json:"chirp_id"`
+	Summary   string        `json:"summary"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// notify records that actor did something of kind to recipient, optionally
+// about a chirp. Acting on yourself never notifies, and repeating the same
+// action is recorded once until the recipient reads it. The notification
+// created, if any, is returned so it can be published once the
+// surrounding transaction commits.
+func notify(ctx context.Context, q *database.Queries, recipient, actor uuid.UUID, kind string, chirpID uuid.NullUUID) ([]database.Notification, error) {
+	if recipient == actor {
+		return nil, nil
+	}
+	notification, err := q.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  recipient,
+		ActorID: actor,
+		Kind:    kind,
+		ChirpID: chirpID,
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []database.Notification{notification}, nil
+}
+
+// notificationSummary describes a notification group in a sentence, e.g.
+// "5 people liked your chirp".
+func notificationSummary(kind string, actorCount int64) string {
+	who := "Someone"
+	if actorCount > 1 {
+		who = fmt.Sprintf("%d people", actorCount)
+	}
+
+	switch kind {
+	case notificationKindLike:
+		return who + " liked your chirp"
+	case notificationKindReply:
+		return who + " replied to your chirp"
+	case notificationKindMention:
+		return who + " mentioned you"
+	case notificationKindFollow:
+		return who + " followed you"
+	case notificationKindRechirp:
+		return who + " rechirped your chirp"
+	}
+	return who + " interacted with you"
+}
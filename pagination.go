@@ -0,0 +1,160 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageCursor is the keyset position a page starts from. It is handed to
+// clients as an opaque base64 string; Before marks a cursor that walks
+// back towards the start of the listing.
+type pageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	Before    bool      `json:"b,omitempty"`
+}
+
+type pageRequest struct {
+	Cursor *pageCursor
+	Limit  int
+	Desc   bool
+}
+
+func encodeCursor(c pageCursor) string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	c := pageCursor{}
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(dat, &c)
+	if err != nil {
+		return c, err
+	}
+	if c.ID == uuid.Nil || c.CreatedAt.IsZero() {
+		return c, errors.New("incomplete cursor")
+	}
+	return c, nil
+}
+
+// parsePageRequest reads the cursor, limit and sort query parameters.
+// Sort defaults to asc; anything other than "desc" is treated as asc.
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	p := pageRequest{
+		Limit: defaultPageSize,
+		Desc:  r.URL.Query().Get("sort") == "desc",
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return p, errors.New("limit must be a positive integer")
+		}
+		p.Limit = min(n, maxPageSize)
+	}
+
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			return p, errors.New("invalid cursor")
+		}
+		p.Cursor = &c
+	}
+
+	return p, nil
+}
+
+// forward reports whether the page walks in the requested sort order.
+func (p pageRequest) forward() bool {
+	return p.Cursor == nil || !p.Cursor.Before
+}
+
+// ascending reports whether rows must be fetched in ascending keyset order.
+func (p pageRequest) ascending() bool {
+	return p.Desc != p.forward()
+}
+
+// keyset returns the cursor position as query parameters, both null when
+// there is no cursor.
+func (p pageRequest) keyset() (sql.NullTime, uuid.NullUUID) {
+	if p.Cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: p.Cursor.CreatedAt, Valid: true},
+		uuid.NullUUID{UUID: p.Cursor.ID, Valid: true}
+}
+
+// fetchSize is the number of rows to query: one more than the page so the
+// extra row tells us whether there is anything past it.
+func (p pageRequest) fetchSize() int32 {
+	return int32(p.Limit + 1)
+}
+
+// paginate trims rows fetched with fetchSize to the page, puts them back
+// into the requested sort order and works out the neighbouring cursors.
+func paginate[T any](p pageRequest, rows []T, key func(T) (time.Time, uuid.UUID)) ([]T, *string, *string) {
+	hasMore := len(rows) > p.Limit
+	if hasMore {
+		rows = rows[:p.Limit]
+	}
+	if !p.forward() {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, nil, nil
+	}
+
+	cursorAt := func(row T, before bool) *string {
+		createdAt, id := key(row)
+		s := encodeCursor(pageCursor{CreatedAt: createdAt, ID: id, Before: before})
+		return &s
+	}
+
+	var next, prev *string
+	if p.forward() {
+		if hasMore {
+			next = cursorAt(rows[len(rows)-1], false)
+		}
+		if p.Cursor != nil {
+			prev = cursorAt(rows[0], true)
+		}
+	} else {
+		if hasMore {
+			prev = cursorAt(rows[0], true)
+		}
+		next = cursorAt(rows[len(rows)-1], false)
+	}
+	return rows, next, prev
+}
+
+// parseFeedPageRequest is parsePageRequest for listings that are always
+// newest first and only page forward.
+func parseFeedPageRequest(r *http.Request) (pageRequest, error) {
+	p, err := parsePageRequest(r)
+	if err != nil {
+		return p, err
+	}
+	if !p.forward() {
+		return p, errors.New("invalid cursor")
+	}
+	p.Desc = true
+	return p, nil
+}
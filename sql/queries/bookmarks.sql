@@ -0,0 +1,65 @@
+-- name: CreateBookmarkFolder :one
+INSERT INTO bookmark_folders (id, created_at, updated_at, user_id, name)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: GetBookmarkFolder :one
+SELECT * FROM bookmark_folders
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListBookmarkFolders :many
+SELECT sqlc.embed(bookmark_folders), COUNT(bookmarks.chirp_id) AS bookmark_count
+FROM bookmark_folders
+LEFT JOIN bookmarks ON bookmarks.folder_id = bookmark_folders.id
+WHERE bookmark_folders.user_id = $1
+GROUP BY bookmark_folders.id
+ORDER BY lower(bookmark_folders.name);
+
+-- name: RenameBookmarkFolder :one
+UPDATE bookmark_folders
+SET name = $1, updated_at = NOW()
+WHERE id = $2 AND user_id = $3
+RETURNING *;
+
+-- name: DeleteBookmarkFolder :execrows
+DELETE FROM bookmark_folders
+WHERE id = $1 AND user_id = $2;
+
+-- name: BookmarkChirp :one
+INSERT INTO bookmarks (user_id, chirp_id, folder_id, created_at)
+VALUES (
+    sqlc.arg('user_id'),
+    sqlc.arg('chirp_id'),
+    sqlc.narg('folder_id'),
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO UPDATE SET folder_id = EXCLUDED.folder_id
+RETURNING *;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: DeleteChirpBookmarks :exec
+DELETE FROM bookmarks
+WHERE chirp_id = $1;
+
+-- name: ListBookmarks :many
+SELECT sqlc.embed(chirp), bookmarks.folder_id, bookmarks.created_at AS bookmarked_at
+FROM bookmarks
+JOIN chirp ON chirp.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = sqlc.arg('user_id')
+AND (sqlc.narg('folder_id')::uuid IS NULL OR bookmarks.folder_id = sqlc.narg('folder_id'))
+AND chirp.deleted_at IS NULL
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (bookmarks.created_at, chirp.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY bookmarks.created_at DESC, chirp.id DESC
+LIMIT sqlc.arg('page_size');
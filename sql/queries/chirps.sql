@@ -1,27 +1,149 @@
 -- name: CreateChirp :one
-INSERT INTO chirp (id, created_at, updated_at, body, user_id)
+INSERT INTO chirp (id, created_at, updated_at, body, user_id, parent_id, root_id, quote_of, is_quote)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
+    sqlc.arg('body'),
+    sqlc.arg('user_id'),
+    sqlc.narg('parent_id'),
+    sqlc.narg('root_id'),
+    sqlc.narg('quote_of'),
+    sqlc.narg('quote_of')::uuid IS NOT NULL
+)
+RETURNING *;
+
+-- name: CreateRechirp :one
+INSERT INTO chirp (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
     $1,
     $2
 )
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
 RETURNING *;
 
 -- name: GetChirp :one
 SELECT * FROM chirp
 WHERE id = $1;
 
--- name: GetChirpsByUserID :many
+-- name: GetChirpForUpdate :one
 SELECT * FROM chirp
-WHERE user_id = $1
-ORDER BY created_at;
+WHERE id = $1
+FOR UPDATE;
 
--- name: GetAllChirps :many
+-- name: ListChirpsAfter :many
 SELECT * FROM chirp
-ORDER BY created_at;
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND deleted_at IS NULL
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListChirpsBefore :many
+SELECT * FROM chirp
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND deleted_at IS NULL
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirp
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: GetThreadChirps :many
+SELECT * FROM chirp
+WHERE id = $1 OR root_id = $1
+ORDER BY created_at ASC, id ASC;
+
+-- name: HasReplies :one
+SELECT EXISTS (
+    SELECT 1 FROM chirp
+    WHERE parent_id = $1
+);
 
 -- name: DeleteChirp :exec
 DELETE FROM chirp
-WHERE id = $1 AND user_id = $2;
\ No newline at end of file
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateChirpBody :one
+UPDATE chirp
+SET
+    body = $1,
+    edited_at = NOW(),
+    updated_at = NOW()
+WHERE
+    id = $2 AND user_id = $3
+RETURNING *;
+
+-- name: DeleteRechirp :one
+DELETE FROM chirp
+WHERE user_id = $1 AND rechirp_of = $2
+RETURNING *;
+
+-- name: DeleteRechirpsOf :many
+DELETE FROM chirp
+WHERE rechirp_of = $1
+RETURNING *;
+
+-- name: AdjustChirpShareCounts :exec
+UPDATE chirp
+SET
+    rechirp_count = rechirp_count + sqlc.arg('rechirp_delta'),
+    quote_count = quote_count + sqlc.arg('quote_delta')
+WHERE
+    id = sqlc.arg('id');
+
+-- name: TombstoneChirp :exec
+UPDATE chirp
+SET
+    body = '',
+    quote_of = NULL,
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE
+    id = $1 AND user_id = $2;
+
+-- name: DeleteOrphanedTombstone :one
+DELETE FROM chirp
+WHERE id = $1
+AND deleted_at IS NOT NULL
+AND NOT EXISTS (
+    SELECT 1 FROM chirp AS replies
+    WHERE replies.parent_id = $1
+)
+RETURNING parent_id;
+
+-- name: ListTimelineAfter :many
+SELECT chirp.* FROM chirp
+JOIN follows ON follows.followee_id = chirp.user_id
+WHERE follows.follower_id = sqlc.arg('follower_id')
+AND chirp.deleted_at IS NULL
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirp.created_at, chirp.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirp.created_at ASC, chirp.id ASC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListTimelineBefore :many
+SELECT chirp.* FROM chirp
+JOIN follows ON follows.followee_id = chirp.user_id
+WHERE follows.follower_id = sqlc.arg('follower_id')
+AND chirp.deleted_at IS NULL
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirp.created_at, chirp.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirp.created_at DESC, chirp.id DESC
+LIMIT sqlc.arg('page_size');
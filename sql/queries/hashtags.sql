@@ -0,0 +1,45 @@
+-- name: CreateHashtags :exec
+INSERT INTO hashtags (id, tag, created_at)
+SELECT gen_random_uuid(), tag, NOW()
+FROM unnest(sqlc.arg('tags')::text[]) AS tag
+ON CONFLICT (tag) DO NOTHING;
+
+-- name: AddChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, hashtag_id, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, hashtags.id, sqlc.arg('created_at')::timestamp
+FROM hashtags
+WHERE hashtags.tag = ANY(sqlc.arg('tags')::text[])
+ON CONFLICT (chirp_id, hashtag_id) DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: ListHashtagChirps :many
+SELECT chirp.* FROM chirp
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirp.id
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE hashtags.tag = sqlc.arg('tag')
+AND chirp.deleted_at IS NULL
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirp.created_at, chirp.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirp.created_at DESC, chirp.id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListTrendingHashtags :many
+SELECT
+    hashtags.tag,
+    COUNT(*) FILTER (WHERE chirp_hashtags.created_at >= sqlc.arg('window_start')::timestamp) AS recent_uses,
+    COUNT(*) FILTER (WHERE chirp_hashtags.created_at < sqlc.arg('window_start')::timestamp) AS baseline_uses
+FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE chirp_hashtags.created_at >= sqlc.arg('baseline_start')::timestamp
+GROUP BY hashtags.tag
+HAVING COUNT(*) FILTER (WHERE chirp_hashtags.created_at >= sqlc.arg('window_start')::timestamp) >= sqlc.arg('min_uses')::bigint
+ORDER BY
+    COUNT(*) FILTER (WHERE chirp_hashtags.created_at >= sqlc.arg('window_start')::timestamp)::float8
+        / (COUNT(*) FILTER (WHERE chirp_hashtags.created_at < sqlc.arg('window_start')::timestamp)::float8 / sqlc.arg('baseline_windows')::float8 + 1) DESC,
+    hashtags.tag ASC
+LIMIT sqlc.arg('max_results');
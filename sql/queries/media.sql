@@ -0,0 +1,49 @@
+-- name: CreateMediaFile :one
+INSERT INTO media_files (id, created_at, user_id, content_type, size_bytes, width, height, storage_key, thumbnail_key, blurhash)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: GetUserMediaFiles :many
+SELECT * FROM media_files
+WHERE user_id = sqlc.arg('user_id')
+AND id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: AddChirpAttachment :exec
+INSERT INTO chirp_attachments (chirp_id, media_id, position, alt_text)
+VALUES ($1, $2, $3, $4);
+
+-- name: DeleteChirpAttachments :exec
+DELETE FROM chirp_attachments
+WHERE chirp_id = $1;
+
+-- name: ListChirpAttachments :many
+SELECT chirp_attachments.chirp_id, chirp_attachments.position, chirp_attachments.alt_text, sqlc.embed(media_files)
+FROM chirp_attachments
+JOIN media_files ON media_files.id = chirp_attachments.media_id
+WHERE chirp_attachments.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_attachments.chirp_id, chirp_attachments.position;
+
+-- name: DeleteUnattachedMediaFiles :many
+DELETE FROM media_files
+WHERE id IN (
+    SELECT id FROM media_files
+    WHERE created_at < sqlc.arg('created_before')
+    AND NOT EXISTS (
+        SELECT 1 FROM chirp_attachments
+        WHERE chirp_attachments.media_id = media_files.id
+    )
+    LIMIT sqlc.arg('max_files')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
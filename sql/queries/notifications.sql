@@ -0,0 +1,53 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, user_id, actor_id, kind, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, kind, actor_id, COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'::uuid)) WHERE read_at IS NULL DO NOTHING
+RETURNING *;
+
+-- name: ListNotificationGroups :many
+SELECT
+    md5(kind || ':' || COALESCE(chirp_id::text, ''))::uuid AS group_id,
+    kind,
+    chirp_id,
+    MAX(created_at)::timestamp AS latest_at,
+    COUNT(*) AS actor_count,
+    COUNT(*) FILTER (WHERE unread) AS unread_count,
+    (array_agg(actor_id ORDER BY created_at DESC))[1:3]::uuid[] AS recent_actor_ids
+FROM (
+    SELECT kind, chirp_id, actor_id, MAX(created_at) AS created_at, bool_or(read_at IS NULL) AS unread
+    FROM notifications
+    WHERE user_id = sqlc.arg('user_id')
+    GROUP BY kind, chirp_id, actor_id
+) AS actors
+GROUP BY kind, chirp_id
+HAVING
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (MAX(created_at), md5(kind || ':' || COALESCE(chirp_id::text, ''))::uuid) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+ORDER BY latest_at DESC, group_id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: CountUnreadNotificationGroups :one
+SELECT COUNT(DISTINCT md5(kind || ':' || COALESCE(chirp_id::text, '')))
+FROM notifications
+WHERE user_id = $1
+AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+AND read_at IS NULL;
+
+-- name: MarkNotificationGroupsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+AND read_at IS NULL
+AND md5(kind || ':' || COALESCE(chirp_id::text, ''))::uuid = ANY(sqlc.arg('group_ids')::uuid[]);
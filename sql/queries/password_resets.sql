@@ -0,0 +1,19 @@
+-- name: ReplacePasswordReset :execrows
+INSERT INTO password_resets (token_hash, user_id, created_at, expires_at)
+VALUES (
+    sqlc.arg('token_hash'),
+    sqlc.arg('user_id'),
+    NOW(),
+    sqlc.arg('expires_at')
+)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    token_hash = EXCLUDED.token_hash,
+    created_at = EXCLUDED.created_at,
+    expires_at = EXCLUDED.expires_at
+WHERE password_resets.created_at < NOW() - sqlc.arg('cooldown_seconds')::int * INTERVAL '1 second';
+
+-- name: ConsumePasswordReset :one
+DELETE FROM password_resets
+WHERE token_hash = $1
+RETURNING *;
@@ -0,0 +1,54 @@
+-- name: LockUserPins :exec
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM pinned_chirps
+WHERE user_id = $1;
+
+-- name: PinChirp :execrows
+INSERT INTO pinned_chirps (user_id, chirp_id, pinned_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: UnpinChirp :exec
+DELETE FROM pinned_chirps
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: DeleteChirpPins :exec
+DELETE FROM pinned_chirps
+WHERE chirp_id = $1;
+
+-- name: ListPinnedChirps :many
+SELECT sqlc.embed(chirp), pinned_chirps.pinned_at
+FROM pinned_chirps
+JOIN chirp ON chirp.id = pinned_chirps.chirp_id
+WHERE pinned_chirps.user_id = sqlc.arg('user_id')
+AND chirp.deleted_at IS NULL
+ORDER BY pinned_chirps.pinned_at DESC, pinned_chirps.chirp_id DESC
+LIMIT sqlc.arg('max_pins');
+
+-- name: ListUserChirps :many
+SELECT * FROM chirp
+WHERE user_id = sqlc.arg('user_id')
+AND deleted_at IS NULL
+AND chirp.id NOT IN (
+    SELECT pinned_chirps.chirp_id
+    FROM pinned_chirps
+    JOIN chirp AS pinned_chirp ON pinned_chirp.id = pinned_chirps.chirp_id
+    WHERE pinned_chirps.user_id = sqlc.arg('user_id')
+    AND pinned_chirp.deleted_at IS NULL
+    ORDER BY pinned_chirps.pinned_at DESC, pinned_chirps.chirp_id DESC
+    LIMIT sqlc.arg('max_pins')
+)
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
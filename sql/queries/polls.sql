@@ -0,0 +1,46 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, created_at, closes_at)
+VALUES (
+    $1,
+    NOW(),
+    $2
+);
+
+-- name: AddPollOption :exec
+INSERT INTO poll_options (chirp_id, position, label)
+VALUES ($1, $2, $3);
+
+-- name: DeletePoll :exec
+DELETE FROM polls
+WHERE chirp_id = $1;
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id = $1;
+
+-- name: CastPollVote :execrows
+INSERT INTO poll_votes (chirp_id, user_id, position, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: ListPolls :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: ListPollOptions :many
+SELECT poll_options.chirp_id, poll_options.position, poll_options.label, COUNT(poll_votes.user_id) AS vote_count
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.chirp_id = poll_options.chirp_id AND poll_votes.position = poll_options.position
+WHERE poll_options.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY poll_options.chirp_id, poll_options.position
+ORDER BY poll_options.chirp_id, poll_options.position;
+
+-- name: ListPollVotes :many
+SELECT chirp_id, position FROM poll_votes
+WHERE user_id = sqlc.arg('user_id')
+AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
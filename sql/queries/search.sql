@@ -0,0 +1,39 @@
+-- name: SearchChirps :many
+SELECT
+    chirp.id,
+    (CASE
+        WHEN sqlc.arg('query')::text = '' THEN 0
+        ELSE ts_rank_cd(to_tsvector('english', chirp.body), websearch_to_tsquery('english', sqlc.arg('query')::text))
+    END)::real AS rank,
+    (CASE
+        WHEN sqlc.arg('query')::text = '' THEN escaped.body
+        ELSE ts_headline('english', escaped.body, websearch_to_tsquery('english', sqlc.arg('query')::text), 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')
+    END)::text AS snippet
+FROM chirp
+JOIN users ON users.id = chirp.user_id
+-- The snippet is HTML, so the body is escaped before the markers go in,
+-- the same way as Go's html.EscapeString. ts_headline reads the escapes
+-- as entities and never highlights inside them.
+CROSS JOIN LATERAL (
+    SELECT replace(replace(replace(replace(replace(chirp.body,
+        '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;') AS body
+) AS escaped
+WHERE chirp.deleted_at IS NULL
+AND chirp.rechirp_of IS NULL
+AND (sqlc.arg('query')::text = '' OR to_tsvector('english', chirp.body) @@ websearch_to_tsquery('english', sqlc.arg('query')::text))
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirp.user_id = sqlc.narg('author_id')::uuid)
+AND (sqlc.narg('author_handle')::text IS NULL OR lower(users.handle) = lower(sqlc.narg('author_handle')::text))
+AND (sqlc.narg('since')::timestamp IS NULL OR chirp.created_at >= sqlc.narg('since')::timestamp)
+AND (sqlc.narg('until')::timestamp IS NULL OR chirp.created_at < sqlc.narg('until')::timestamp)
+AND (
+    cardinality(sqlc.arg('tags')::text[]) = 0
+    OR (
+        SELECT COUNT(*) FROM chirp_hashtags
+        JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+        WHERE chirp_hashtags.chirp_id = chirp.id
+        AND hashtags.tag = ANY(sqlc.arg('tags')::text[])
+    ) = cardinality(sqlc.arg('tags')::text[])
+)
+ORDER BY rank DESC, chirp.created_at DESC, chirp.id DESC
+LIMIT sqlc.arg('page_size')
+OFFSET sqlc.arg('page_offset');
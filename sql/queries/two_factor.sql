@@ -0,0 +1,90 @@
+-- name: StartTOTPEnrollment :one
+INSERT INTO user_totp (user_id, secret, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    secret = EXCLUDED.secret,
+    created_at = EXCLUDED.created_at,
+    last_step = 0,
+    failed_attempts = 0,
+    locked_until = NULL
+WHERE user_totp.enabled_at IS NULL
+RETURNING *;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp
+WHERE user_id = $1;
+
+-- name: EnableTOTP :execrows
+UPDATE user_totp
+SET
+    enabled_at = NOW(),
+    last_step = $2,
+    failed_attempts = 0
+WHERE
+    user_id = $1
+    AND secret = $3
+    AND enabled_at IS NULL;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_totp
+SET
+    last_step = $2,
+    failed_attempts = 0,
+    locked_until = NULL
+WHERE
+    user_id = $1
+    AND last_step < $2;
+
+-- name: ClaimMFAAttempt :one
+UPDATE user_totp
+SET
+    failed_attempts = CASE
+        WHEN failed_attempts + 1 >= sqlc.arg('max_attempts') THEN 0
+        ELSE failed_attempts + 1
+    END,
+    locked_until = CASE
+        WHEN failed_attempts + 1 >= sqlc.arg('max_attempts') THEN sqlc.arg('locked_until')::timestamp
+        ELSE NULL
+    END
+WHERE
+    user_id = sqlc.arg('user_id')
+    AND (locked_until IS NULL OR locked_until <= sqlc.arg('now')::timestamp)
+RETURNING *;
+
+-- name: ResetMFAFailures :exec
+UPDATE user_totp
+SET
+    failed_attempts = 0,
+    locked_until = NULL
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (user_id, code_hash, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+);
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+DELETE FROM recovery_codes
+WHERE
+    user_id = $1
+    AND code_hash = $2;
+
+-- name: CountRecoveryCodes :one
+SELECT COUNT(*) FROM recovery_codes
+WHERE user_id = $1;
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -16,21 +17,92 @@ DELETE FROM users;
 SELECT * FROM users
 WHERE email = $1;
 
--- name: UpdateUserLoginInfo :one
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: GetUsersByHandles :many
+SELECT id, handle FROM users
+WHERE lower(handle) = ANY(sqlc.arg('handles')::text[]);
+
+-- name: UpdateUserChirpyRed :one
+UPDATE users
+SET
+    is_chirpy_red = true,
+    updated_at = NOW()
+WHERE
+    id = $1
+RETURNING *;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE lower(handle) = lower(sqlc.arg('handle'));
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    handle = $1,
+    display_name = $2,
+    bio = $3,
+    website = $4,
+    updated_at = NOW()
+WHERE
+    id = $5
+RETURNING *;
+
+-- name: GetUserProfile :one
+SELECT
+    sqlc.embed(users),
+    (SELECT COUNT(*) FROM chirp WHERE chirp.user_id = users.id AND chirp.deleted_at IS NULL) AS chirp_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following_count
+FROM users
+WHERE users.id = $1;
+
+-- name: SetUserAvatar :one
+UPDATE users
+SET
+    avatar_key = sqlc.narg('avatar_key'),
+    updated_at = NOW()
+FROM (SELECT id, avatar_key FROM users WHERE id = sqlc.arg('id') FOR UPDATE) AS previous
+WHERE users.id = previous.id
+RETURNING previous.avatar_key;
+
+-- name: SetUserBanner :one
+UPDATE users
+SET
+    banner_key = sqlc.narg('banner_key'),
+    updated_at = NOW()
+FROM (SELECT id, banner_key FROM users WHERE id = sqlc.arg('id') FOR UPDATE) AS previous
+WHERE users.id = previous.id
+RETURNING previous.banner_key;
+
+-- name: UpdateUserEmail :one
 UPDATE users
 SET
     email = $1,
-    hashed_password = $2,
+    email_verified_at = NOW(),
     updated_at = NOW()
 WHERE
-    id = $3
+    id = $2
 RETURNING *;
 
--- name: UpdateUserChirpyRed :one
+-- name: UpdateUserPassword :one
 UPDATE users
 SET
-    is_chirpy_red = true,
+    hashed_password = $1,
+    updated_at = NOW()
+WHERE
+    id = $2
+RETURNING *;
+
+-- name: MarkUserEmailVerified :one
+UPDATE users
+SET
+    email_verified_at = NOW(),
     updated_at = NOW()
 WHERE
     id = $1
-RETURNING *;
\ No newline at end of file
+    AND email = $2
+    AND email_verified_at IS NULL
+RETURNING *;
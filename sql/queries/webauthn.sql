@@ -0,0 +1,69 @@
+-- name: CreateWebAuthnChallenge :exec
+INSERT INTO webauthn_challenges (challenge, user_id, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    $3
+);
+
+-- name: ConsumeWebAuthnChallenge :one
+DELETE FROM webauthn_challenges
+WHERE challenge = $1
+RETURNING *;
+
+-- name: DeleteExpiredWebAuthnChallenges :exec
+DELETE FROM webauthn_challenges
+WHERE expires_at < $1;
+
+-- name: UseWebAuthnChallenge :execrows
+INSERT INTO webauthn_used_challenges (challenge, expires_at)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT (challenge) DO NOTHING;
+
+-- name: DeleteExpiredUsedWebAuthnChallenges :exec
+DELETE FROM webauthn_used_challenges
+WHERE expires_at < $1;
+
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (id, user_id, name, public_key, sign_count, aaguid, backup_eligible, backup_state, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetWebAuthnCredential :one
+SELECT * FROM webauthn_credentials
+WHERE id = $1;
+
+-- name: ListUserWebAuthnCredentials :many
+SELECT * FROM webauthn_credentials
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: UpdateWebAuthnCredentialUse :execrows
+UPDATE webauthn_credentials
+SET
+    sign_count = sqlc.arg('sign_count'),
+    backup_state = sqlc.arg('backup_state'),
+    last_used_at = NOW()
+WHERE
+    id = sqlc.arg('id')
+    AND sign_count = sqlc.arg('previous_sign_count');
+
+-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE
+    id = $1
+    AND user_id = $2;
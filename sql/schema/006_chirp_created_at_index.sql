@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE INDEX chirp_created_at_id_idx ON chirp (created_at, id);
+CREATE INDEX chirp_user_id_created_at_id_idx ON chirp (user_id, created_at, id);
+
+-- +goose Down
+DROP INDEX chirp_user_id_created_at_id_idx;
+DROP INDEX chirp_created_at_id_idx;
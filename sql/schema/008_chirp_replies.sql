@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE chirp
+ADD COLUMN parent_id UUID REFERENCES chirp(id) ON DELETE SET NULL,
+ADD COLUMN root_id UUID REFERENCES chirp(id) ON DELETE SET NULL,
+ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX chirp_parent_id_idx ON chirp (parent_id);
+CREATE INDEX chirp_root_id_idx ON chirp (root_id);
+
+-- +goose Down
+ALTER TABLE chirp
+DROP COLUMN deleted_at,
+DROP COLUMN root_id,
+DROP COLUMN parent_id;
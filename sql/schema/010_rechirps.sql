@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE chirp
+ADD COLUMN rechirp_of UUID REFERENCES chirp(id) ON DELETE CASCADE,
+ADD COLUMN quote_of UUID REFERENCES chirp(id) ON DELETE SET NULL,
+ADD COLUMN is_quote BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN rechirp_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN quote_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX chirp_user_id_rechirp_of_idx ON chirp (user_id, rechirp_of)
+WHERE rechirp_of IS NOT NULL;
+CREATE INDEX chirp_rechirp_of_idx ON chirp (rechirp_of);
+
+-- +goose Down
+ALTER TABLE chirp
+DROP COLUMN quote_count,
+DROP COLUMN rechirp_count,
+DROP COLUMN is_quote,
+DROP COLUMN quote_of,
+DROP COLUMN rechirp_of;
@@ -0,0 +1,34 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN handle TEXT;
+
+CREATE UNIQUE INDEX users_lower_handle_idx ON users (lower(handle));
+
+CREATE TABLE chirp_mentions (
+    chirp_id UUID NOT NULL REFERENCES chirp(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_offset INTEGER NOT NULL,
+    end_offset INTEGER NOT NULL,
+    PRIMARY KEY (chirp_id, start_offset)
+);
+
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    chirp_id UUID REFERENCES chirp(id) ON DELETE CASCADE,
+    read_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX notifications_user_id_kind_actor_id_chirp_id_idx ON notifications (user_id, kind, actor_id, chirp_id);
+
+-- +goose Down
+DROP TABLE notifications;
+DROP TABLE chirp_mentions;
+
+ALTER TABLE users
+DROP COLUMN handle;
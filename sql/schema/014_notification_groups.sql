@@ -0,0 +1,20 @@
+-- +goose Up
+DROP INDEX notifications_user_id_kind_actor_id_chirp_id_idx;
+
+-- Repeating an action adds nothing while its notification is still
+-- unread, but once read, a new one can come in.
+CREATE UNIQUE INDEX notifications_user_id_kind_actor_id_chirp_id_idx ON notifications (
+    user_id,
+    kind,
+    actor_id,
+    COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'::uuid)
+)
+WHERE read_at IS NULL;
+
+CREATE INDEX notifications_user_id_created_at_idx ON notifications (user_id, created_at);
+
+-- +goose Down
+DROP INDEX notifications_user_id_created_at_idx;
+DROP INDEX notifications_user_id_kind_actor_id_chirp_id_idx;
+
+CREATE UNIQUE INDEX notifications_user_id_kind_actor_id_chirp_id_idx ON notifications (user_id, kind, actor_id, chirp_id);
@@ -0,0 +1,8 @@
+-- +goose Up
+-- An expression index rather than a stored column, so chirp rows and
+-- every query reading them stay the same size. Queries must use this
+-- exact expression for the planner to pick the index.
+CREATE INDEX chirp_search_idx ON chirp USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX chirp_search_idx;
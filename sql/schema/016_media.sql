@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE media_files (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    blurhash TEXT NOT NULL
+);
+
+CREATE TABLE chirp_attachments (
+    chirp_id UUID NOT NULL REFERENCES chirp(id) ON DELETE CASCADE,
+    media_id UUID NOT NULL REFERENCES media_files(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL CHECK (position BETWEEN 0 AND 3),
+    alt_text TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (chirp_id, position),
+    UNIQUE (chirp_id, media_id)
+);
+
+CREATE INDEX chirp_attachments_media_id_idx ON chirp_attachments (media_id);
+
+-- +goose Down
+DROP TABLE chirp_attachments;
+DROP TABLE media_files;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Each key is the prefix shared by every size of the image.
+ALTER TABLE users
+ADD COLUMN avatar_key TEXT,
+ADD COLUMN banner_key TEXT;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN banner_key,
+DROP COLUMN avatar_key;
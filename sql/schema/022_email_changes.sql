@@ -0,0 +1,15 @@
+-- +goose Up
+-- A pending change of address, confirmed by the token sent to new_email.
+-- Only a hash of the token is kept.
+CREATE TABLE email_changes (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    new_email TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX email_changes_user_id_idx ON email_changes (user_id);
+
+-- +goose Down
+DROP TABLE email_changes;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- An outstanding password reset. Only a hash of the token mailed to the
+-- user is kept. An account has at most one, so a new request can replace
+-- it, or be turned away while it is still fresh, in a single statement.
+CREATE TABLE password_resets (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX password_resets_user_id_key ON password_resets (user_id);
+
+-- +goose Down
+DROP TABLE password_resets;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- A user's TOTP authenticator. enabled_at stays NULL until enrollment is
+-- confirmed with a code. last_step is the newest time step a code has
+-- been accepted for, so no code works twice.
+CREATE TABLE user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    enabled_at TIMESTAMP,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP
+);
+
+-- One-time codes for signing in without the authenticator. code_hash is
+-- an HMAC-SHA256 of the user ID and the code, keyed with the server
+-- secret, so a leaked table can't be brute-forced offline.
+CREATE TABLE recovery_codes (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+DROP TABLE user_totp;
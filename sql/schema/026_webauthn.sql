@@ -0,0 +1,39 @@
+-- +goose Up
+-- Passkeys. id is the credential ID the authenticator chose and
+-- public_key its COSE-encoded key.
+CREATE TABLE webauthn_credentials (
+    id BYTEA PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL DEFAULT '',
+    public_key BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL,
+    aaguid BYTEA NOT NULL,
+    backup_eligible BOOLEAN NOT NULL,
+    backup_state BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+-- Challenges handed out to signed-in users registering a passkey, keyed
+-- by their base64url form as it comes back in the client data.
+CREATE TABLE webauthn_challenges (
+    challenge TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- Login challenges are signed and carry their own expiry, so handing one
+-- out stores nothing. Only challenges answered by a successful login are
+-- kept, until they expire, so none can be answered twice.
+CREATE TABLE webauthn_used_challenges (
+    challenge TEXT PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE webauthn_used_challenges;
+DROP TABLE webauthn_challenges;
+DROP TABLE webauthn_credentials;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Every refresh hands out a new token in the same family and points the
+-- old one at it through replaced_by. A token that already has a
+-- replacement being used again means it leaked, and the family is revoked.
+ALTER TABLE refresh_tokens
+ADD COLUMN family_id UUID,
+ADD COLUMN replaced_by TEXT REFERENCES refresh_tokens(token) ON DELETE SET NULL;
+
+-- Each existing token starts a family of its own.
+UPDATE refresh_tokens SET family_id = gen_random_uuid();
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP COLUMN replaced_by,
+DROP COLUMN family_id;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Keep only a SHA-256 of each refresh token, as for password resets.
+-- Hashing the existing rows in place, the same way auth.HashToken does,
+-- keeps every session working. Both columns change in one statement so
+-- the replaced_by foreign key still holds when it is checked.
+UPDATE refresh_tokens
+SET
+    token = encode(sha256(convert_to(token, 'UTF8')), 'hex'),
+    replaced_by = encode(sha256(convert_to(replaced_by, 'UTF8')), 'hex');
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+-- +goose Down
+-- The hashes can't be turned back into tokens, so going back logs
+-- everyone out.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;